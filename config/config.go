@@ -9,11 +9,120 @@ type JwtOptions struct {
 	Timeout     time.Duration
 	MaxRefresh  time.Duration
 	IdentityKey string
+
+	// Algorithm is the signing algorithm of issued tokens, one of HS256,
+	// HS384, HS512, RS256, RS384, RS512, ES256, ES384, ES512 or EdDSA.
+	// It defaults to HS256, the HMAC algorithms sign with Key
+	Algorithm string
+
+	// PrivateKey and PublicKey are the PEM encoded keys of the RSA, ECDSA
+	// and EdDSA algorithms. The public key is derived from the private key
+	// when only the private key is set, a service which only verifies
+	// tokens needs only the public key
+	PrivateKey string
+	PublicKey  string
+
+	// PrivateKeyFile and PublicKeyFile are paths the PEM encoded keys are
+	// read from when PrivateKey or PublicKey are empty
+	PrivateKeyFile string
+	PublicKeyFile  string
+
+	// Keys is a set of signing keys identified by the kid header of tokens,
+	// it allows keys to be rotated without invalidating issued tokens.
+	// When Keys is empty the single key configured above is used
+	Keys []JwtKey
+
+	// CurrentKeyID is the ID of the key in Keys which signs new tokens,
+	// it defaults to the last key
+	CurrentKeyID string
+
+	// Issuer is stamped as the iss claim of issued tokens, tokens from
+	// another issuer are rejected when it is set
+	Issuer string
+
+	// Audience is stamped as the aud claim of issued tokens, when it is set
+	// tokens are rejected unless their aud names one of its audiences
+	Audience []string
+
+	// Leeway is the clock skew tolerated when checking exp, nbf and iat
+	Leeway time.Duration
+
+	// RefreshGracePeriod is how long after a refresh the rotated refresh
+	// token may be presented again, e.g. by a client whose response was
+	// lost, and get the same new token pair instead of revoking the family.
+	// Zero disables replays
+	RefreshGracePeriod time.Duration
+
+	// MaxSessionLifetime is how long after the user authenticated a refresh
+	// token family may be refreshed, however active it is. Zero disables it
+	MaxSessionLifetime time.Duration
+
+	// SessionIdleTimeout is how long a refresh token family may go without
+	// being refreshed before it can no longer be. Zero disables it, the
+	// family then stays usable until its refresh token expires
+	SessionIdleTimeout time.Duration
+}
+
+// JwtKey is a key of the JwtOptions key set, its fields mean the same as
+// the fields of JwtOptions with the same names
+type JwtKey struct {
+	ID             string
+	Algorithm      string
+	Key            string
+	PrivateKey     string
+	PublicKey      string
+	PrivateKeyFile string
+	PublicKeyFile  string
+
+	// RetireAt is when tokens signed with the key stop being accepted,
+	// the key is used until it is removed when RetireAt is zero
+	RetireAt time.Time
+}
+
+// MailConfig selects and configures the transport used to deliver mail
+type MailConfig struct {
+	// Transport is one of "smtp", "sendgrid", "mailgun" or "ses".
+	// It defaults to smtp when empty
+	Transport   string
+	SenderName  string
+	SenderEmail string
+
+	// Host, Port, Username and Password are used by the smtp transport
+	Host     string
+	Port     int
+	Username string
+	Password string
+
+	// DKIMDomain, DKIMSelector and DKIMPrivateKey enable DKIM signing of
+	// mail sent by the smtp transport. DKIMPrivateKey is a PEM encoded
+	// RSA or Ed25519 private key, signing is disabled when it is empty
+	DKIMDomain     string
+	DKIMSelector   string
+	DKIMPrivateKey string
+
+	// APIKey is the API key used by the sendgrid and mailgun transports
+	APIKey string
+
+	// Domain is the sending domain registered with mailgun
+	Domain string
+
+	// Region, AccessKeyID and SecretAccessKey are used by the ses transport
+	Region          string
+	AccessKeyID     string
+	SecretAccessKey string
+
+	// Endpoint overrides the base url of the HTTP API transports,
+	// e.g. to use the EU region of a provider
+	Endpoint string
 }
 
 type AuthConfig struct {
 	UserIdClaim        string
 	AuthUserContextKey string
+
+	// AuthClaimsContextKey is the context key the claims of the
+	// authenticated token are stored under, it is left unchanged when empty
+	AuthClaimsContextKey string
 }
 
 // CORSConfig contains the settings used to setup CORS for the app
@@ -43,13 +152,18 @@ type CORSConfig struct {
 
 var UserIdClaim string
 var AuthUserContextKey string
+var AuthClaimsContextKey string
 
 func init() {
 	UserIdClaim = "uid"
 	AuthUserContextKey = "authusercontext"
+	AuthClaimsContextKey = "authclaimscontext"
 }
 
 func Setup(cfg AuthConfig) {
 	UserIdClaim = cfg.UserIdClaim
 	AuthUserContextKey = cfg.AuthUserContextKey
+	if cfg.AuthClaimsContextKey != "" {
+		AuthClaimsContextKey = cfg.AuthClaimsContextKey
+	}
 }
@@ -1,9 +1,12 @@
 package controller
 
 import (
+	"time"
+
 	"github.com/dino16m/golearn-core/bus"
 	"github.com/dino16m/golearn-core/errors"
 	"github.com/dino16m/golearn-core/event"
+	"github.com/dino16m/golearn-core/services"
 	"github.com/gin-gonic/gin"
 )
 
@@ -23,14 +26,27 @@ type UserService interface {
 
 type AuthController struct {
 	BaseController
-	userService UserService
-	bus         *bus.EventBus
+	userService   UserService
+	bus           *bus.EventBus
+	revoker       services.TokenRevoker
+	userID        func(user interface{}) string
+	tokenLifetime time.Duration
 }
 
 func NewAuthController(userService UserService, bus *bus.EventBus) AuthController {
 	return AuthController{userService: userService, bus: bus}
 }
 
+// SetTokenRevoker makes ChangePassword revoke every token issued to the
+// user so far. userID returns the ID the tokens of the user carry as their
+// sub claim and tokenLifetime is how long the longest lived of them stays
+// valid, usually MaxRefresh
+func (ctrl *AuthController) SetTokenRevoker(revoker services.TokenRevoker, userID func(user interface{}) string, tokenLifetime time.Duration) {
+	ctrl.revoker = revoker
+	ctrl.userID = userID
+	ctrl.tokenLifetime = tokenLifetime
+}
+
 func (ctrl AuthController) Signup(c *gin.Context) {
 	user, err := ctrl.userService.CreateUser(c)
 	if err != nil {
@@ -60,6 +76,14 @@ func (ctrl AuthController) ChangePassword(c *gin.Context) {
 		ctrl.ErrorResponse(c, err)
 		return
 	}
+	if ctrl.revoker != nil {
+		now := time.Now()
+		if err := ctrl.revoker.RevokeUserTokens(ctrl.userID(user), now, now.Add(ctrl.tokenLifetime)); err != nil {
+			ctrl.ErrorResponse(c, errors.InternalServerError("Could not revoke tokens"))
+			return
+		}
+	}
+	ctrl.bus.Dispatch(event.NewPasswordChangedEvent(user))
 	ctrl.OkResponse(c, AppResponse{})
 
 }
@@ -0,0 +1,85 @@
+package controller
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/dino16m/golearn-core/bus"
+	"github.com/dino16m/golearn-core/config"
+	"github.com/dino16m/golearn-core/errors"
+	"github.com/dino16m/golearn-core/services"
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/suite"
+)
+
+type passwordUserService struct{}
+
+func (passwordUserService) CreateUser(ctx Validatable) (interface{}, errors.ApplicationError) {
+	return nil, nil
+}
+
+func (passwordUserService) ChangePassword(user interface{}, dto PasswordChangeForm) errors.ApplicationError {
+	return nil
+}
+
+// failingRevoker fails to store watermarks
+type failingRevoker struct {
+	services.TokenRevoker
+}
+
+func (failingRevoker) RevokeUserTokens(userID string, issuedBefore time.Time, expiresAt time.Time) error {
+	return fmt.Errorf("revocation store is down")
+}
+
+type authControllerTestSuite struct {
+	suite.Suite
+	blacklister *services.InMemoryTokenBlacklister
+}
+
+func (s *authControllerTestSuite) SetupTest() {
+	gin.SetMode(gin.TestMode)
+	s.blacklister = services.NewInMemoryTokenBlacklister()
+}
+
+func (s *authControllerTestSuite) TearDownTest() {
+	s.blacklister.Close()
+}
+
+func (s *authControllerTestSuite) changePassword(revoker services.TokenRevoker) *httptest.ResponseRecorder {
+	ctrl := NewAuthController(passwordUserService{}, bus.NewEventBus())
+	ctrl.SetTokenRevoker(revoker, func(user interface{}) string { return user.(string) }, time.Hour)
+	router := gin.New()
+	router.Use(func(c *gin.Context) { c.Set(config.AuthUserContextKey, "user-1") })
+	ctrl.RegisterRoutes(&router.RouterGroup)
+	req := httptest.NewRequest(http.MethodPost, "/change-password",
+		strings.NewReader(`{"oldPassword": "old", "newPassword": "new"}`))
+	req.Header.Set("Content-Type", "application/json")
+	res := httptest.NewRecorder()
+	router.ServeHTTP(res, req)
+	return res
+}
+
+func (s *authControllerTestSuite) TestChangePasswordRevokesTokens() {
+	issuedAt := time.Now()
+
+	res := s.changePassword(s.blacklister)
+
+	s.Equal(http.StatusOK, res.Code)
+	revoked, err := s.blacklister.IsRevoked("jti", "user-1", issuedAt)
+	s.NoError(err)
+	s.True(revoked)
+}
+
+func (s *authControllerTestSuite) TestChangePasswordFailsWhenTokensAreNotRevoked() {
+	res := s.changePassword(failingRevoker{s.blacklister})
+
+	s.Equal(http.StatusInternalServerError, res.Code)
+}
+
+func TestAuthController(t *testing.T) {
+	suite.Run(t, new(authControllerTestSuite))
+}
@@ -6,6 +6,7 @@ import (
 
 	"github.com/dino16m/golearn-core/config"
 	"github.com/dino16m/golearn-core/errors"
+	"github.com/dino16m/golearn-core/services"
 	"github.com/gin-gonic/gin"
 )
 
@@ -42,6 +43,20 @@ func GetUser[T any](c *gin.Context) (T, errors.ApplicationError) {
 	return user.(T), nil
 }
 
+// GetClaims returns the claims of the token the request was authenticated
+// with, C must be the custom claims type of the JWTAuthMiddleware
+func GetClaims[C any](c *gin.Context) (services.Claims[C], errors.ApplicationError) {
+	value, exists := c.Get(config.AuthClaimsContextKey)
+	if !exists {
+		return services.Claims[C]{}, errors.UnauthorizedError("User not authenticated")
+	}
+	claims, ok := value.(services.Claims[C])
+	if !ok {
+		return services.Claims[C]{}, errors.InternalServerError("Unexpected claims type")
+	}
+	return claims, nil
+}
+
 // GetAuthUser returns the authenticated user interface and a nil error
 // if such user exists.
 // It returns a nil user and an error if the user does not exist or if
@@ -61,10 +76,14 @@ func (b BaseController) GetBaseURL(c *gin.Context) string {
 
 func (b BaseController) ErrorResponse(ctx *gin.Context, err errors.ApplicationError) {
 	code, message := err.Resolve()
-	ctx.JSON(code, gin.H{
+	body := gin.H{
 		"status": false,
 		"error":  message,
-	})
+	}
+	if reason, ok := errors.ErrorCodeOf(err); ok {
+		body["code"] = reason
+	}
+	ctx.JSON(code, body)
 }
 
 func (b BaseController) OkResponse(ctx *gin.Context, res AppResponse) {
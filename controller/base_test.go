@@ -0,0 +1,52 @@
+package controller
+
+import (
+	"net/http/httptest"
+	"testing"
+
+	"github.com/dino16m/golearn-core/config"
+	"github.com/dino16m/golearn-core/services"
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/suite"
+)
+
+type roleClaims struct {
+	Role string `json:"role"`
+}
+
+type baseControllerTestSuite struct {
+	suite.Suite
+	ctx *gin.Context
+}
+
+func (s *baseControllerTestSuite) SetupTest() {
+	gin.SetMode(gin.TestMode)
+	s.ctx, _ = gin.CreateTestContext(httptest.NewRecorder())
+}
+
+func (s *baseControllerTestSuite) TestGetClaimsReturnsTypedClaims() {
+	s.ctx.Set(config.AuthClaimsContextKey, services.Claims[roleClaims]{Custom: roleClaims{Role: "admin"}})
+
+	claims, err := GetClaims[roleClaims](s.ctx)
+
+	s.Require().Nil(err)
+	s.Equal("admin", claims.Custom.Role)
+}
+
+func (s *baseControllerTestSuite) TestGetClaimsWithoutAuthentication() {
+	_, err := GetClaims[roleClaims](s.ctx)
+
+	s.NotNil(err)
+}
+
+func (s *baseControllerTestSuite) TestGetClaimsWithWrongType() {
+	s.ctx.Set(config.AuthClaimsContextKey, services.Claims[services.JWTClaims]{})
+
+	_, err := GetClaims[roleClaims](s.ctx)
+
+	s.NotNil(err)
+}
+
+func TestBaseController(t *testing.T) {
+	suite.Run(t, new(baseControllerTestSuite))
+}
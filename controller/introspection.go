@@ -0,0 +1,137 @@
+package controller
+
+import (
+	"crypto/subtle"
+	"encoding/json"
+	"net/http"
+	"strings"
+
+	"github.com/dino16m/golearn-core/errors"
+	"github.com/dino16m/golearn-core/services"
+	"github.com/dino16m/golearn-core/types"
+	"github.com/gin-gonic/gin"
+)
+
+// ClientAuthenticator checks the credentials of the clients allowed to
+// introspect tokens
+type ClientAuthenticator interface {
+	AuthenticateClient(clientID string, secret string) bool
+}
+
+// StaticClients is a ClientAuthenticator of a fixed set of clients,
+// mapping client IDs to their secrets
+type StaticClients map[string]string
+
+func (clients StaticClients) AuthenticateClient(clientID string, secret string) bool {
+	expected, ok := clients[clientID]
+	return ok && subtle.ConstantTimeCompare([]byte(expected), []byte(secret)) == 1
+}
+
+type IntrospectionPayload struct {
+	Token         string `form:"token" binding:"required"`
+	TokenTypeHint string `form:"token_type_hint"`
+}
+
+// IntrospectionResponse describes a token as defined by RFC 7662, only
+// Active is set for tokens which are not active. Both token kinds are
+// bearer tokens, the token_use extension tells access and refresh tokens
+// apart
+type IntrospectionResponse struct {
+	Active    bool              `json:"active"`
+	Scope     string            `json:"scope,omitempty"`
+	TokenType string            `json:"token_type,omitempty"`
+	Exp       int64             `json:"exp,omitempty"`
+	Iat       int64             `json:"iat,omitempty"`
+	Nbf       int64             `json:"nbf,omitempty"`
+	Sub       string            `json:"sub,omitempty"`
+	Aud       services.Audience `json:"aud,omitempty"`
+	Iss       string            `json:"iss,omitempty"`
+	Jti       string            `json:"jti,omitempty"`
+	AuthTime  int64             `json:"auth_time,omitempty"`
+	TokenUse  string            `json:"token_use,omitempty"`
+}
+
+// SetIntrospectionClients enables the introspection endpoint for the
+// clients authenticated by clients, it must be set before the routes are
+// registered
+func (ctrl *JWTAuthController[C]) SetIntrospectionClients(clients ClientAuthenticator) {
+	ctrl.introspectionClients = clients
+}
+
+// Introspect implements OAuth 2.0 token introspection (RFC 7662). Clients
+// authenticate with HTTP Basic, the response is the bare introspection
+// document without the usual response envelope
+func (ctrl JWTAuthController[C]) Introspect(c *gin.Context) {
+	clientID, secret, ok := c.Request.BasicAuth()
+	if !ok || ctrl.introspectionClients == nil || !ctrl.introspectionClients.AuthenticateClient(clientID, secret) {
+		c.Header("WWW-Authenticate", `Basic realm="introspection"`)
+		ctrl.ErrorResponse(c, errors.UnauthorizedError("Invalid client credentials"))
+		return
+	}
+	var payload IntrospectionPayload
+	if err := c.ShouldBind(&payload); err != nil {
+		ctrl.ErrorResponse(c, errors.ValidationError(err.Error()))
+		return
+	}
+
+	c.Header("Cache-Control", "no-store")
+	claims, err := ctrl.authService.GetClaim(payload.Token)
+	if err != nil {
+		c.JSON(http.StatusOK, IntrospectionResponse{})
+		return
+	}
+	active, storeErr := ctrl.authService.Active(claims.RegisteredClaims)
+	if storeErr != nil {
+		ctrl.ErrorResponse(c, errors.InternalServerError("Could not check token revocation"))
+		return
+	}
+	if !active {
+		c.JSON(http.StatusOK, IntrospectionResponse{})
+		return
+	}
+	c.JSON(http.StatusOK, IntrospectionResponse{
+		Active:    true,
+		Scope:     scopeOf(claims),
+		TokenType: "Bearer",
+		Exp:       claims.ExpiresAt,
+		Iat:       claims.IssuedAt,
+		Nbf:       claims.NotBefore,
+		Sub:       claims.Subject,
+		Aud:       claims.Audience,
+		Iss:       claims.Issuer,
+		Jti:       claims.ID,
+		AuthTime:  claims.AuthTime,
+		TokenUse:  tokenUseOf(claims.Use),
+	})
+}
+
+func tokenUseOf(use string) string {
+	switch use {
+	case types.AuthTokenKey:
+		return "access_token"
+	case types.RefreshTokenKey:
+		return "refresh_token"
+	}
+	return ""
+}
+
+// scopeOf returns the scopes of the custom claims as a space separated
+// list, they are read from a scope claim holding a string or a scopes
+// claim holding a list
+func scopeOf[C any](claims services.Claims[C]) string {
+	encoded, err := json.Marshal(claims.Custom)
+	if err != nil {
+		return ""
+	}
+	var custom struct {
+		Scope  interface{} `json:"scope"`
+		Scopes []string    `json:"scopes"`
+	}
+	if json.Unmarshal(encoded, &custom) != nil {
+		return ""
+	}
+	if scope, ok := custom.Scope.(string); ok {
+		return scope
+	}
+	return strings.Join(custom.Scopes, " ")
+}
@@ -0,0 +1,142 @@
+package controller
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/dino16m/golearn-core/config"
+	"github.com/dino16m/golearn-core/services"
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/suite"
+)
+
+type introspectionTestSuite struct {
+	suite.Suite
+	blacklister *services.InMemoryTokenBlacklister
+	service     services.JWTAuthService[services.JWTClaims]
+	router      *gin.Engine
+}
+
+func (s *introspectionTestSuite) SetupTest() {
+	gin.SetMode(gin.TestMode)
+	options := config.JwtOptions{Key: "secret", Timeout: time.Minute, MaxRefresh: time.Hour}
+	s.blacklister = services.NewInMemoryTokenBlacklister()
+	service, err := services.NewJWTAuthService[services.JWTClaims](options, s.blacklister)
+	s.Require().NoError(err)
+	s.service = service
+	ctrl := NewJWTAuthController[services.JWTClaims](service, staticAuthenticator{userId: "user-1"})
+	ctrl.SetIntrospectionClients(StaticClients{"billing": "s3cret"})
+	s.router = gin.New()
+	ctrl.RegisterRoutes(&s.router.RouterGroup)
+}
+
+func (s *introspectionTestSuite) TearDownTest() {
+	s.blacklister.Close()
+}
+
+func (s *introspectionTestSuite) login() (refresh string, auth string) {
+	refresh, auth, err := s.service.GetTokenPair(services.Claims[services.JWTClaims]{
+		RegisteredClaims: services.RegisteredClaims{UserID: "user-1"},
+		Custom:           services.JWTClaims{"scopes": []string{"read", "write"}},
+	}, services.Client{})
+	s.Require().NoError(err)
+	return refresh, auth
+}
+
+func (s *introspectionTestSuite) introspect(token string, clientID string, secret string) *httptest.ResponseRecorder {
+	form := url.Values{"token": {token}}
+	req := httptest.NewRequest(http.MethodPost, "/introspect", strings.NewReader(form.Encode()))
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	if clientID != "" {
+		req.SetBasicAuth(clientID, secret)
+	}
+	res := httptest.NewRecorder()
+	s.router.ServeHTTP(res, req)
+	return res
+}
+
+func (s *introspectionTestSuite) decode(res *httptest.ResponseRecorder) map[string]interface{} {
+	s.Require().Equal(http.StatusOK, res.Code)
+	var body map[string]interface{}
+	s.Require().NoError(json.Unmarshal(res.Body.Bytes(), &body))
+	return body
+}
+
+func (s *introspectionTestSuite) TestActiveAuthToken() {
+	_, auth := s.login()
+
+	body := s.decode(s.introspect(auth, "billing", "s3cret"))
+
+	s.Equal(true, body["active"])
+	s.Equal("user-1", body["sub"])
+	s.Equal("read write", body["scope"])
+	s.Equal("Bearer", body["token_type"])
+	s.Equal("access_token", body["token_use"])
+	s.NotEmpty(body["exp"])
+	s.NotEmpty(body["jti"])
+}
+
+func (s *introspectionTestSuite) TestRotatedRefreshTokenIsInactive() {
+	refresh, _ := s.login()
+	body := s.decode(s.introspect(refresh, "billing", "s3cret"))
+	s.Equal(true, body["active"])
+	s.Equal("Bearer", body["token_type"])
+	s.Equal("refresh_token", body["token_use"])
+
+	_, err := s.service.RefreshToken(refresh, services.Client{})
+	s.Require().Nil(err)
+
+	s.Equal(map[string]interface{}{"active": false}, s.decode(s.introspect(refresh, "billing", "s3cret")))
+}
+
+func (s *introspectionTestSuite) TestRevokedAuthTokenIsInactive() {
+	_, auth := s.login()
+	claims, _ := s.service.GetClaim(auth)
+	s.Require().Nil(s.service.Logout(claims))
+
+	body := s.decode(s.introspect(auth, "billing", "s3cret"))
+
+	s.Equal(map[string]interface{}{"active": false}, body)
+}
+
+func (s *introspectionTestSuite) TestInvalidTokenIsInactive() {
+	body := s.decode(s.introspect("not-a-token", "billing", "s3cret"))
+
+	s.Equal(map[string]interface{}{"active": false}, body)
+}
+
+func (s *introspectionTestSuite) TestClientMustAuthenticate() {
+	_, auth := s.login()
+
+	for _, credentials := range [][2]string{{"", ""}, {"billing", "wrong"}, {"unknown", "s3cret"}} {
+		res := s.introspect(auth, credentials[0], credentials[1])
+		s.Equal(http.StatusUnauthorized, res.Code)
+		s.NotEmpty(res.Header().Get("WWW-Authenticate"))
+		s.NotContains(res.Body.String(), "active")
+	}
+}
+
+func (s *introspectionTestSuite) TestTokenIsRequired() {
+	res := s.introspect("", "billing", "s3cret")
+
+	s.Equal(http.StatusBadRequest, res.Code)
+}
+
+func (s *introspectionTestSuite) TestDisabledWithoutClients() {
+	router := gin.New()
+	NewJWTAuthController[services.JWTClaims](s.service, staticAuthenticator{}).RegisterRoutes(&router.RouterGroup)
+	res := httptest.NewRecorder()
+
+	router.ServeHTTP(res, httptest.NewRequest(http.MethodPost, "/introspect", nil))
+
+	s.Equal(http.StatusNotFound, res.Code)
+}
+
+func TestIntrospection(t *testing.T) {
+	suite.Run(t, new(introspectionTestSuite))
+}
@@ -0,0 +1,35 @@
+package controller
+
+import (
+	"net/http"
+
+	"github.com/dino16m/golearn-core/services"
+	"github.com/gin-gonic/gin"
+)
+
+// JWKSProvider returns the public keys tokens can be verified with
+type JWKSProvider interface {
+	JWKS() services.JWKS
+}
+
+// JWKSController publishes the public signing keys of a JWTAuthService
+// so other services can verify its tokens
+type JWKSController struct {
+	BaseController
+	provider JWKSProvider
+}
+
+func NewJWKSController(provider JWKSProvider) JWKSController {
+	return JWKSController{provider: provider}
+}
+
+// JWKS serves the key set as a bare JWK Set document, clients expect it
+// without the usual response envelope
+func (ctrl JWKSController) JWKS(c *gin.Context) {
+	c.Header("Cache-Control", "public, max-age=300")
+	c.JSON(http.StatusOK, ctrl.provider.JWKS())
+}
+
+func (ctrl JWKSController) RegisterRoutes(router *gin.RouterGroup) {
+	router.GET("/.well-known/jwks.json", ctrl.JWKS)
+}
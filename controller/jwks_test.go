@@ -0,0 +1,46 @@
+package controller
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/dino16m/golearn-core/services"
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/suite"
+)
+
+type staticJWKS services.JWKS
+
+func (k staticJWKS) JWKS() services.JWKS {
+	return services.JWKS(k)
+}
+
+type jwksControllerTestSuite struct {
+	suite.Suite
+	router *gin.Engine
+	jwks   services.JWKS
+}
+
+func (s *jwksControllerTestSuite) SetupTest() {
+	gin.SetMode(gin.TestMode)
+	s.jwks = services.JWKS{Keys: []services.JWK{{Kty: "OKP", Kid: "k1", Crv: "Ed25519", X: "abc"}}}
+	s.router = gin.New()
+	NewJWKSController(staticJWKS(s.jwks)).RegisterRoutes(&s.router.RouterGroup)
+}
+
+func (s *jwksControllerTestSuite) TestServesKeySet() {
+	res := httptest.NewRecorder()
+	s.router.ServeHTTP(res, httptest.NewRequest(http.MethodGet, "/.well-known/jwks.json", nil))
+
+	s.Equal(http.StatusOK, res.Code)
+	var body services.JWKS
+	s.Require().NoError(json.Unmarshal(res.Body.Bytes(), &body))
+	s.Equal(s.jwks, body)
+	s.NotEmpty(res.Header().Get("Cache-Control"))
+}
+
+func TestJWKSController(t *testing.T) {
+	suite.Run(t, new(jwksControllerTestSuite))
+}
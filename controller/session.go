@@ -0,0 +1,81 @@
+package controller
+
+import (
+	"github.com/dino16m/golearn-core/errors"
+	"github.com/dino16m/golearn-core/services"
+	"github.com/gin-gonic/gin"
+)
+
+// SessionResponse is a session of the authenticated user, Current marks
+// the session the request was made from
+type SessionResponse struct {
+	services.Session
+	Current bool `json:"current"`
+}
+
+// SessionController lists and revokes the sessions of the authenticated
+// user, its routes must be registered behind the JWTAuthMiddleware
+// authenticating tokens whose custom claims are C
+type SessionController[C any] struct {
+	BaseController
+	store services.SessionStore
+}
+
+func NewSessionController[C any](store services.SessionStore) SessionController[C] {
+	return SessionController[C]{store: store}
+}
+
+func (ctrl SessionController[C]) ListSessions(c *gin.Context) {
+	claims, err := GetClaims[C](c)
+	if err != nil {
+		ctrl.ErrorResponse(c, err)
+		return
+	}
+	sessions, storeErr := ctrl.store.ListSessions(claims.Subject)
+	if storeErr != nil {
+		ctrl.ErrorResponse(c, errors.InternalServerError("Could not list sessions"))
+		return
+	}
+	response := make([]SessionResponse, len(sessions))
+	for i, session := range sessions {
+		response[i] = SessionResponse{Session: session, Current: session.Family == claims.Family}
+	}
+	ctrl.OkResponse(c, AppResponse{Data: response})
+}
+
+func (ctrl SessionController[C]) RevokeSession(c *gin.Context) {
+	claims, err := GetClaims[C](c)
+	if err != nil {
+		ctrl.ErrorResponse(c, err)
+		return
+	}
+	revoked, storeErr := ctrl.store.RevokeSession(claims.Subject, c.Param("id"))
+	if storeErr != nil {
+		ctrl.ErrorResponse(c, errors.InternalServerError("Could not revoke session"))
+		return
+	}
+	if !revoked {
+		ctrl.ErrorResponse(c, errors.NotFoundError("Session not found"))
+		return
+	}
+	ctrl.OkResponse(c, AppResponse{})
+}
+
+func (ctrl SessionController[C]) RevokeAllSessions(c *gin.Context) {
+	claims, err := GetClaims[C](c)
+	if err != nil {
+		ctrl.ErrorResponse(c, err)
+		return
+	}
+	if storeErr := ctrl.store.RevokeAllSessions(claims.Subject); storeErr != nil {
+		ctrl.ErrorResponse(c, errors.InternalServerError("Could not revoke sessions"))
+		return
+	}
+	ctrl.OkResponse(c, AppResponse{})
+}
+
+func (ctrl SessionController[C]) RegisterRoutes(router *gin.RouterGroup) {
+	router.GET("/sessions", ctrl.ListSessions)
+	router.DELETE("/sessions/:id", ctrl.RevokeSession)
+	router.DELETE("/sessions", ctrl.RevokeAllSessions)
+}
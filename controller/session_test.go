@@ -0,0 +1,109 @@
+package controller
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/dino16m/golearn-core/config"
+	"github.com/dino16m/golearn-core/services"
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/suite"
+)
+
+type fakeSessionStore struct {
+	*services.InMemoryTokenBlacklister
+	sessions map[string][]services.Session
+}
+
+func (store *fakeSessionStore) SaveSession(session services.Session, jti string, expiresAt time.Time) error {
+	store.sessions[session.UserID] = append(store.sessions[session.UserID], session)
+	return nil
+}
+
+func (store *fakeSessionStore) ListSessions(userID string) ([]services.Session, error) {
+	return store.sessions[userID], nil
+}
+
+func (store *fakeSessionStore) RevokeSession(userID string, family string) (bool, error) {
+	for i, session := range store.sessions[userID] {
+		if session.Family == family {
+			store.sessions[userID] = append(store.sessions[userID][:i], store.sessions[userID][i+1:]...)
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+func (store *fakeSessionStore) RevokeAllSessions(userID string) error {
+	delete(store.sessions, userID)
+	return nil
+}
+
+type sessionControllerTestSuite struct {
+	suite.Suite
+	store  *fakeSessionStore
+	router *gin.Engine
+}
+
+func (s *sessionControllerTestSuite) SetupTest() {
+	gin.SetMode(gin.TestMode)
+	s.store = &fakeSessionStore{
+		InMemoryTokenBlacklister: services.NewInMemoryTokenBlacklister(),
+		sessions: map[string][]services.Session{
+			"user-1": {{Family: "current", UserID: "user-1"}, {Family: "other", UserID: "user-1"}},
+		},
+	}
+	s.router = gin.New()
+	authenticated := s.router.Group("/", func(c *gin.Context) {
+		c.Set(config.AuthClaimsContextKey, services.Claims[services.JWTClaims]{
+			RegisteredClaims: services.RegisteredClaims{Subject: "user-1", Family: "current"},
+		})
+	})
+	NewSessionController[services.JWTClaims](s.store).RegisterRoutes(authenticated)
+}
+
+func (s *sessionControllerTestSuite) request(method string, path string) *httptest.ResponseRecorder {
+	res := httptest.NewRecorder()
+	s.router.ServeHTTP(res, httptest.NewRequest(method, path, nil))
+	return res
+}
+
+func (s *sessionControllerTestSuite) TestListSessionsMarksCurrent() {
+	res := s.request(http.MethodGet, "/sessions")
+
+	s.Equal(http.StatusOK, res.Code)
+	var body struct {
+		Data []SessionResponse `json:"data"`
+	}
+	s.Require().NoError(json.Unmarshal(res.Body.Bytes(), &body))
+	s.Require().Len(body.Data, 2)
+	s.True(body.Data[0].Current)
+	s.False(body.Data[1].Current)
+}
+
+func (s *sessionControllerTestSuite) TestRevokeSession() {
+	res := s.request(http.MethodDelete, "/sessions/other")
+
+	s.Equal(http.StatusOK, res.Code)
+	s.Len(s.store.sessions["user-1"], 1)
+}
+
+func (s *sessionControllerTestSuite) TestRevokeUnknownSession() {
+	res := s.request(http.MethodDelete, "/sessions/unknown")
+
+	s.Equal(http.StatusNotFound, res.Code)
+}
+
+func (s *sessionControllerTestSuite) TestRevokeAllSessions() {
+	res := s.request(http.MethodDelete, "/sessions")
+
+	s.Equal(http.StatusOK, res.Code)
+	s.Empty(s.store.sessions["user-1"])
+}
+
+func TestSessionController(t *testing.T) {
+	suite.Run(t, new(sessionControllerTestSuite))
+}
@@ -2,10 +2,11 @@ package controller
 
 import (
 	"net/http"
+	"strings"
 
-	"github.com/dino16m/golearn-core/config"
 	"github.com/dino16m/golearn-core/errors"
 	"github.com/dino16m/golearn-core/services"
+	"github.com/dino16m/golearn-core/types"
 	"github.com/gin-gonic/gin"
 )
 
@@ -13,28 +14,33 @@ type Authenticator interface {
 	Authenticate(c Validatable) (userId interface{}, err errors.ApplicationError)
 }
 
-type JWTAuthService interface {
-	GetTokenPair(claim map[string]interface{}) (refreshToken string, authToken string)
-	GetToken(claim map[string]interface{}) string
-	GetClaim(tokenStr string) (map[string]interface{}, errors.ApplicationError)
-	RefreshToken(refreshToken string) (services.TokenPair, errors.ApplicationError)
+type JWTAuthService[C any] interface {
+	GetTokenPair(claims services.Claims[C], client services.Client) (refreshToken string, authToken string, err error)
+	GetToken(claims services.Claims[C]) (string, error)
+	GetClaim(tokenStr string) (services.Claims[C], errors.ApplicationError)
+	RefreshToken(refreshToken string, client services.Client) (services.TokenPair, errors.ApplicationError)
+	Logout(claims services.Claims[C]) errors.ApplicationError
+	LogoutAll(claims services.Claims[C]) errors.ApplicationError
+	Active(claims services.RegisteredClaims) (bool, error)
+	Revoked(claims services.RegisteredClaims) (bool, error)
 }
 
 type RefreshTokenPayload struct {
 	Token string `form:"token" json:"token" binding:"required"`
 }
 
-type JWTAuthController struct {
+type JWTAuthController[C any] struct {
 	BaseController
-	authenticator Authenticator
-	authService   JWTAuthService
+	authenticator        Authenticator
+	authService          JWTAuthService[C]
+	introspectionClients ClientAuthenticator
 }
 
-func NewJWTAuthController(authService JWTAuthService, authenticator Authenticator) JWTAuthController {
-	return JWTAuthController{authenticator: authenticator, authService: authService}
+func NewJWTAuthController[C any](authService JWTAuthService[C], authenticator Authenticator) JWTAuthController[C] {
+	return JWTAuthController[C]{authenticator: authenticator, authService: authService}
 }
 
-func (ctrl JWTAuthController) RefreshToken(c *gin.Context) {
+func (ctrl JWTAuthController[C]) RefreshToken(c *gin.Context) {
 	var refresh RefreshTokenPayload
 	if err := c.ShouldBind(&refresh); err != nil {
 		c.JSON(http.StatusBadRequest, gin.H{
@@ -44,7 +50,7 @@ func (ctrl JWTAuthController) RefreshToken(c *gin.Context) {
 		return
 	}
 
-	pair, err := ctrl.authService.RefreshToken(refresh.Token)
+	pair, err := ctrl.authService.RefreshToken(refresh.Token, clientOf(c))
 	if err != nil {
 		ctrl.ErrorResponse(c, err)
 		return
@@ -52,16 +58,24 @@ func (ctrl JWTAuthController) RefreshToken(c *gin.Context) {
 	ctrl.OkResponse(c, AppResponse{Data: pair})
 }
 
-func (ctrl JWTAuthController) GetTokenPair(c *gin.Context) {
+func (ctrl JWTAuthController[C]) GetTokenPair(c *gin.Context) {
 	userId, err := ctrl.authenticator.Authenticate(c)
 	if err != nil {
 		ctrl.ErrorResponse(c, err)
 		return
 	}
-	claim := map[string]interface{}{
-		config.UserIdClaim: userId,
+	claims := services.Claims[C]{
+		RegisteredClaims: services.RegisteredClaims{UserID: userId},
+	}
+	refreshToken, authToken, issueErr := ctrl.authService.GetTokenPair(claims, clientOf(c))
+	if appErr, ok := issueErr.(errors.ApplicationError); ok {
+		ctrl.ErrorResponse(c, appErr)
+		return
+	}
+	if issueErr != nil {
+		ctrl.ErrorResponse(c, errors.InternalServerError("Could not issue tokens"))
+		return
 	}
-	refreshToken, authToken := ctrl.authService.GetTokenPair(claim)
 	response := map[string]string{
 		"refreshToken": refreshToken,
 		"authToken":    authToken,
@@ -69,7 +83,78 @@ func (ctrl JWTAuthController) GetTokenPair(c *gin.Context) {
 	ctrl.OkResponse(c, AppResponse{Data: response})
 }
 
-func (ctrl JWTAuthController) RegisterRoutes(router *gin.RouterGroup) {
+// Logout revokes the auth token of the request and its session
+func (ctrl JWTAuthController[C]) Logout(c *gin.Context) {
+	claims, err := ctrl.authClaims(c)
+	if err != nil {
+		ctrl.ErrorResponse(c, err)
+		return
+	}
+	if err := ctrl.authService.Logout(claims); err != nil {
+		ctrl.ErrorResponse(c, err)
+		return
+	}
+	ctrl.OkResponse(c, AppResponse{})
+}
+
+// LogoutAll revokes every token of the user the request is made for
+func (ctrl JWTAuthController[C]) LogoutAll(c *gin.Context) {
+	claims, err := ctrl.authClaims(c)
+	if err != nil {
+		ctrl.ErrorResponse(c, err)
+		return
+	}
+	if err := ctrl.authService.LogoutAll(claims); err != nil {
+		ctrl.ErrorResponse(c, err)
+		return
+	}
+	ctrl.OkResponse(c, AppResponse{})
+}
+
+// authClaims verifies the bearer auth token of the request, revoked
+// tokens are rejected
+func (ctrl JWTAuthController[C]) authClaims(c *gin.Context) (services.Claims[C], errors.ApplicationError) {
+	token, ok := bearerToken(c)
+	if !ok {
+		return services.Claims[C]{}, errors.UnauthorizedError("Unauthorized")
+	}
+	claims, err := ctrl.authService.GetClaim(token)
+	if err != nil {
+		return services.Claims[C]{}, err
+	}
+	if claims.Use != types.AuthTokenKey {
+		return services.Claims[C]{}, errors.UnauthorizedError("This is not an auth token")
+	}
+	revoked, checkErr := ctrl.authService.Revoked(claims.RegisteredClaims)
+	if checkErr != nil {
+		return services.Claims[C]{}, errors.InternalServerError("Could not check token revocation")
+	}
+	if revoked {
+		return services.Claims[C]{}, errors.UnauthorizedError("Token has been revoked")
+	}
+	return claims, nil
+}
+
+func (ctrl JWTAuthController[C]) RegisterRoutes(router *gin.RouterGroup) {
 	router.POST("/login", ctrl.GetTokenPair)
 	router.POST("/refresh-token", ctrl.RefreshToken)
+	router.POST("/logout", ctrl.Logout)
+	router.POST("/logout-all", ctrl.LogoutAll)
+	if ctrl.introspectionClients != nil {
+		router.POST("/introspect", ctrl.Introspect)
+	}
+}
+
+// bearerToken returns the token of the Authorization header
+func bearerToken(c *gin.Context) (string, bool) {
+	scheme, token, ok := strings.Cut(c.GetHeader("Authorization"), " ")
+	if !ok || !strings.EqualFold(scheme, "Bearer") || token == "" {
+		return "", false
+	}
+	return token, true
+}
+
+// clientOf describes the client making the request
+func clientOf(c *gin.Context) services.Client {
+	return services.Client{UserAgent: c.Request.UserAgent(), IP: c.ClientIP()}
 }
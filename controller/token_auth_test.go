@@ -0,0 +1,204 @@
+package controller
+
+import (
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/x509"
+	"encoding/json"
+	"encoding/pem"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/dino16m/golearn-core/config"
+	"github.com/dino16m/golearn-core/errors"
+	"github.com/dino16m/golearn-core/services"
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/suite"
+)
+
+type staticAuthenticator struct {
+	userId interface{}
+}
+
+func (a staticAuthenticator) Authenticate(c Validatable) (interface{}, errors.ApplicationError) {
+	return a.userId, nil
+}
+
+// endedSessionService refuses every refresh with err
+type endedSessionService struct {
+	services.JWTAuthService[services.JWTClaims]
+	err errors.ApplicationError
+}
+
+func (e endedSessionService) RefreshToken(refreshToken string, client services.Client) (services.TokenPair, errors.ApplicationError) {
+	return services.TokenPair{}, e.err
+}
+
+type jwtAuthControllerTestSuite struct {
+	suite.Suite
+	options     config.JwtOptions
+	blacklister *services.InMemoryTokenBlacklister
+	service     services.JWTAuthService[services.JWTClaims]
+	router      *gin.Engine
+}
+
+func (s *jwtAuthControllerTestSuite) SetupTest() {
+	gin.SetMode(gin.TestMode)
+	s.options = config.JwtOptions{Key: "secret", Timeout: time.Minute, MaxRefresh: time.Hour}
+	s.blacklister = services.NewInMemoryTokenBlacklister()
+	service, err := services.NewJWTAuthService[services.JWTClaims](s.options, s.blacklister)
+	s.Require().NoError(err)
+	s.service = service
+	s.router = s.routes(service)
+}
+
+func (s *jwtAuthControllerTestSuite) TearDownTest() {
+	s.blacklister.Close()
+}
+
+func (s *jwtAuthControllerTestSuite) routes(service services.JWTAuthService[services.JWTClaims]) *gin.Engine {
+	router := gin.New()
+	NewJWTAuthController[services.JWTClaims](service, staticAuthenticator{userId: "user-1"}).
+		RegisterRoutes(&router.RouterGroup)
+	return router
+}
+
+func (s *jwtAuthControllerTestSuite) login(router *gin.Engine) *httptest.ResponseRecorder {
+	res := httptest.NewRecorder()
+	router.ServeHTTP(res, httptest.NewRequest(http.MethodPost, "/login", nil))
+	return res
+}
+
+func (s *jwtAuthControllerTestSuite) tokenPair() (refresh string, auth string) {
+	refresh, auth, err := s.service.GetTokenPair(services.Claims[services.JWTClaims]{
+		RegisteredClaims: services.RegisteredClaims{UserID: "user-1"},
+	}, services.Client{})
+	s.Require().NoError(err)
+	return refresh, auth
+}
+
+func (s *jwtAuthControllerTestSuite) post(path string, authorization string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(http.MethodPost, path, nil)
+	req.Header.Set("Authorization", authorization)
+	res := httptest.NewRecorder()
+	s.router.ServeHTTP(res, req)
+	return res
+}
+
+func (s *jwtAuthControllerTestSuite) TestLoginIssuesTokenPair() {
+	res := s.login(s.router)
+
+	s.Equal(http.StatusOK, res.Code)
+	var body struct {
+		Data map[string]string `json:"data"`
+	}
+	s.Require().NoError(json.Unmarshal(res.Body.Bytes(), &body))
+	s.NotEmpty(body.Data["refreshToken"])
+	s.NotEmpty(body.Data["authToken"])
+}
+
+func (s *jwtAuthControllerTestSuite) TestLoginFailsWhenTokensCannotBeIssued() {
+	public, _, _ := ed25519.GenerateKey(rand.Reader)
+	der, _ := x509.MarshalPKIXPublicKey(public)
+	options := s.options
+	options.Algorithm = "EdDSA"
+	options.PublicKey = string(pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: der}))
+	service, err := services.NewJWTAuthService[services.JWTClaims](options, s.blacklister)
+	s.Require().NoError(err)
+
+	res := s.login(s.routes(service))
+
+	s.Equal(http.StatusInternalServerError, res.Code)
+	s.NotContains(res.Body.String(), "authToken")
+}
+
+func (s *jwtAuthControllerTestSuite) refresh(router *gin.Engine, token string) (int, string) {
+	req := httptest.NewRequest(http.MethodPost, "/refresh-token", strings.NewReader(`{"token": "`+token+`"}`))
+	req.Header.Set("Content-Type", "application/json")
+	res := httptest.NewRecorder()
+	router.ServeHTTP(res, req)
+	var body struct {
+		Code string `json:"code"`
+	}
+	s.Require().NoError(json.Unmarshal(res.Body.Bytes(), &body))
+	return res.Code, body.Code
+}
+
+func (s *jwtAuthControllerTestSuite) TestRefreshReportsExpiredSession() {
+	refresh, _, err := s.service.GetTokenPair(services.Claims[services.JWTClaims]{
+		RegisteredClaims: services.RegisteredClaims{UserID: "user-1", AuthTime: time.Now().Add(-2 * time.Hour).Unix()},
+	}, services.Client{})
+	s.Require().NoError(err)
+	options := s.options
+	options.MaxSessionLifetime = time.Hour
+	service, err := services.NewJWTAuthService[services.JWTClaims](options, s.blacklister)
+	s.Require().NoError(err)
+
+	status, code := s.refresh(s.routes(service), refresh)
+
+	s.Equal(http.StatusUnauthorized, status)
+	s.Equal("session_expired", code)
+}
+
+func (s *jwtAuthControllerTestSuite) TestRefreshReportsIdleSession() {
+	refresh, _ := s.tokenPair()
+	service := endedSessionService{JWTAuthService: s.service, err: services.ErrSessionIdle}
+	router := gin.New()
+	NewJWTAuthController[services.JWTClaims](service, staticAuthenticator{userId: "user-1"}).
+		RegisterRoutes(&router.RouterGroup)
+
+	status, code := s.refresh(router, refresh)
+
+	s.Equal(http.StatusUnauthorized, status)
+	s.Equal("session_idle", code)
+}
+
+func (s *jwtAuthControllerTestSuite) TestRefreshErrorsWithoutReasonHaveNoCode() {
+	status, code := s.refresh(s.router, "not-a-token")
+
+	s.Equal(http.StatusUnauthorized, status)
+	s.Empty(code)
+}
+
+func (s *jwtAuthControllerTestSuite) TestLogoutRevokesSession() {
+	refresh, auth := s.tokenPair()
+
+	res := s.post("/logout", "Bearer "+auth)
+
+	s.Equal(http.StatusOK, res.Code)
+	claims, _ := s.service.GetClaim(auth)
+	revoked, _ := s.service.Revoked(claims.RegisteredClaims)
+	s.True(revoked)
+	_, refreshErr := s.service.RefreshToken(refresh, services.Client{})
+	s.NotNil(refreshErr)
+}
+
+func (s *jwtAuthControllerTestSuite) TestLogoutRequiresAuthToken() {
+	refresh, _ := s.tokenPair()
+
+	for _, header := range []string{"", "Bearer", "Bearer " + refresh} {
+		res := s.post("/logout-all", header)
+		s.Equal(http.StatusUnauthorized, res.Code, header)
+	}
+}
+
+func (s *jwtAuthControllerTestSuite) TestLogoutAllRejectsRevokedToken() {
+	_, stolen := s.tokenPair()
+	_, other := s.tokenPair()
+	stolenClaims, _ := s.service.GetClaim(stolen)
+	s.Require().Nil(s.service.Logout(stolenClaims))
+
+	res := s.post("/logout-all", "Bearer "+stolen)
+
+	s.Equal(http.StatusUnauthorized, res.Code)
+	otherClaims, _ := s.service.GetClaim(other)
+	revoked, _ := s.service.Revoked(otherClaims.RegisteredClaims)
+	s.False(revoked)
+}
+
+func TestJWTAuthController(t *testing.T) {
+	suite.Run(t, new(jwtAuthControllerTestSuite))
+}
@@ -0,0 +1,46 @@
+package controller
+
+import (
+	"github.com/dino16m/golearn-core/bus"
+	"github.com/dino16m/golearn-core/errors"
+	"github.com/dino16m/golearn-core/event"
+	"github.com/dino16m/golearn-core/mail"
+	"github.com/gin-gonic/gin"
+)
+
+// UnsubscribeController processes the unsubscribe links created
+// by a mail.UnsubscribeSigner
+type UnsubscribeController struct {
+	BaseController
+	signer *mail.UnsubscribeSigner
+	bus    *bus.EventBus
+}
+
+func NewUnsubscribeController(signer *mail.UnsubscribeSigner, bus *bus.EventBus) UnsubscribeController {
+	return UnsubscribeController{signer: signer, bus: bus}
+}
+
+// OneClickUnsubscribe handles the RFC 8058 one-click unsubscribe POST mail
+// clients send to the List-Unsubscribe url, it dispatches an
+// event.Unsubscribed for the address and list in the token
+func (ctrl UnsubscribeController) OneClickUnsubscribe(c *gin.Context) {
+	if c.PostForm("List-Unsubscribe") != "One-Click" {
+		ctrl.ErrorResponse(c, errors.ValidationError("List-Unsubscribe=One-Click is required"))
+		return
+	}
+	token := c.Query("token")
+	if token == "" {
+		token = c.PostForm("token")
+	}
+	claims, err := ctrl.signer.Verify(token)
+	if err != nil {
+		ctrl.ErrorResponse(c, errors.UnauthorizedError(err.Error()))
+		return
+	}
+	ctrl.bus.Dispatch(event.NewUnsubscribedEvent(claims.Email, claims.List))
+	ctrl.OkResponse(c, AppResponse{})
+}
+
+func (ctrl UnsubscribeController) RegisterRoutes(router *gin.RouterGroup) {
+	router.POST("/unsubscribe", ctrl.OneClickUnsubscribe)
+}
@@ -0,0 +1,68 @@
+package controller
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/dino16m/golearn-core/bus"
+	"github.com/dino16m/golearn-core/event"
+	"github.com/dino16m/golearn-core/mail"
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/suite"
+)
+
+type unsubscribeControllerTestSuite struct {
+	suite.Suite
+	signer   *mail.UnsubscribeSigner
+	listener *bus.MockListener
+	router   *gin.Engine
+}
+
+func (s *unsubscribeControllerTestSuite) SetupTest() {
+	gin.SetMode(gin.TestMode)
+	s.signer = mail.NewUnsubscribeSigner("secret", time.Hour)
+	eventBus := bus.NewEventBus()
+	s.listener = bus.NewMockListener(s.T())
+	eventBus.AddListener(event.Unsubscribed{}, s.listener)
+	s.router = gin.New()
+	NewUnsubscribeController(s.signer, eventBus).RegisterRoutes(&s.router.RouterGroup)
+}
+
+func (s *unsubscribeControllerTestSuite) post(token string, body string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(http.MethodPost, "/unsubscribe?token="+url.QueryEscape(token), strings.NewReader(body))
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	res := httptest.NewRecorder()
+	s.router.ServeHTTP(res, req)
+	return res
+}
+
+func (s *unsubscribeControllerTestSuite) TestOneClickUnsubscribeDispatchesEvent() {
+	s.listener.On("Handle", mock.Anything)
+
+	res := s.post(s.signer.Token("me@me.com", "digest"), "List-Unsubscribe=One-Click")
+
+	s.Equal(http.StatusOK, res.Code)
+	s.listener.AssertCalled(s.T(), "Handle", event.NewUnsubscribedEvent("me@me.com", "digest"))
+}
+
+func (s *unsubscribeControllerTestSuite) TestInvalidTokenRejected() {
+	res := s.post("forged.token", "List-Unsubscribe=One-Click")
+
+	s.Equal(http.StatusUnauthorized, res.Code)
+	s.listener.AssertNotCalled(s.T(), "Handle", mock.Anything)
+}
+
+func (s *unsubscribeControllerTestSuite) TestOneClickBodyRequired() {
+	res := s.post(s.signer.Token("me@me.com", "digest"), "")
+
+	s.Equal(http.StatusBadRequest, res.Code)
+}
+
+func TestUnsubscribeController(t *testing.T) {
+	suite.Run(t, new(unsubscribeControllerTestSuite))
+}
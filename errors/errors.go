@@ -22,6 +22,32 @@ func (e AppError) Resolve() (code int, message string) {
 	return e.Code, e.Message
 }
 
+// CodedError is an AppError with a machine readable Reason, clients tell
+// errors apart by the reason rather than by the message
+type CodedError struct {
+	AppError
+	Reason string
+}
+
+// ErrorCode returns the machine readable reason of the error
+func (e CodedError) ErrorCode() string {
+	return e.Reason
+}
+
+// WithCode attaches the machine readable reason to err
+func WithCode(err AppError, reason string) CodedError {
+	return CodedError{AppError: err, Reason: reason}
+}
+
+// ErrorCodeOf returns the machine readable reason of err, if it has one
+func ErrorCodeOf(err ApplicationError) (string, bool) {
+	coded, ok := err.(interface{ ErrorCode() string })
+	if !ok {
+		return "", false
+	}
+	return coded.ErrorCode(), true
+}
+
 func UnauthorizedError(message string) AppError {
 	return AppError{Code: http.StatusUnauthorized, Message: message}
 }
@@ -33,3 +59,7 @@ func InternalServerError(message string) AppError {
 func ValidationError(message string) AppError {
 	return AppError{Code: 400, Message: message}
 }
+
+func NotFoundError(message string) AppError {
+	return AppError{Code: http.StatusNotFound, Message: message}
+}
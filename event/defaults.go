@@ -7,3 +7,13 @@ type UserCreated struct {
 func NewUserCreatedEvent(payload any) UserCreated {
 	return UserCreated{Payload: payload}
 }
+
+// PasswordChanged is dispatched after a user changed their password,
+// the payload is the user
+type PasswordChanged struct {
+	Payload any
+}
+
+func NewPasswordChangedEvent(payload any) PasswordChanged {
+	return PasswordChanged{Payload: payload}
+}
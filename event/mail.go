@@ -0,0 +1,32 @@
+package event
+
+// MailSent is dispatched after a mail was handed to the mail provider,
+// the payload is the sent message
+type MailSent struct {
+	Payload any
+}
+
+func NewMailSentEvent(payload any) MailSent {
+	return MailSent{Payload: payload}
+}
+
+// MailFailed is dispatched when sending a mail failed,
+// the payload is the message which was not sent
+type MailFailed struct {
+	Payload any
+	Err     error
+}
+
+func NewMailFailedEvent(payload any, err error) MailFailed {
+	return MailFailed{Payload: payload, Err: err}
+}
+
+// Unsubscribed is dispatched when a recipient unsubscribes from a mailing list
+type Unsubscribed struct {
+	Email string
+	List  string
+}
+
+func NewUnsubscribedEvent(email string, list string) Unsubscribed {
+	return Unsubscribed{Email: email, List: list}
+}
@@ -0,0 +1,203 @@
+package mail
+
+import (
+	"errors"
+	"fmt"
+	"net/textproto"
+)
+
+// SendMode decides whether a batch stops at the first message which fails
+type SendMode int
+
+const (
+	// StopOnError does not attempt the messages after a failed message
+	StopOnError SendMode = iota
+	// ContinueOnError attempts every message regardless of earlier failures
+	ContinueOnError
+)
+
+// SendStatus is the outcome of sending a single message of a batch
+type SendStatus int
+
+const (
+	// StatusSent means the transport accepted the message
+	StatusSent SendStatus = iota
+	// StatusVetoed means a before send hook dropped the message
+	StatusVetoed
+	// StatusRejectedRecipient means a recipient address was refused
+	StatusRejectedRecipient
+	// StatusTransportError means the transport failed to send the message
+	StatusTransportError
+	// StatusSkipped means the message was not attempted because an
+	// earlier message failed in StopOnError mode
+	StatusSkipped
+)
+
+func (s SendStatus) String() string {
+	switch s {
+	case StatusSent:
+		return "sent"
+	case StatusVetoed:
+		return "vetoed"
+	case StatusRejectedRecipient:
+		return "rejected recipient"
+	case StatusTransportError:
+		return "transport error"
+	case StatusSkipped:
+		return "skipped"
+	}
+	return "unknown"
+}
+
+// ErrNotAttempted is the error of messages skipped in StopOnError mode
+var ErrNotAttempted = errors.New("mail: not attempted because an earlier message failed")
+
+// RecipientError is returned when a recipient address of a message is invalid
+type RecipientError struct {
+	Address string
+	Err     error
+}
+
+func (e RecipientError) Error() string {
+	return fmt.Sprintf("mail: recipient %q rejected: %v", e.Address, e.Err)
+}
+
+func (e RecipientError) Unwrap() error {
+	return e.Err
+}
+
+// BatchTransport is a Transport which reports the outcome of every message
+// it sends, it returns one error per message, nil for sent messages
+type BatchTransport interface {
+	Transport
+	DeliverEach(sender Sender, mode SendMode, msgs ...SendableMessage) []error
+}
+
+// MessageResult is the outcome of sending one message of a batch
+type MessageResult struct {
+	Message SendableMessage
+	Status  SendStatus
+	Err     error
+}
+
+// BatchResult lists the outcome of every message of a batch in the order
+// the messages were given
+type BatchResult struct {
+	Results []MessageResult
+}
+
+// Sent returns the messages accepted by the transport
+func (r BatchResult) Sent() []SendableMessage {
+	sent := []SendableMessage{}
+	for _, result := range r.Results {
+		if result.Status == StatusSent {
+			sent = append(sent, result.Message)
+		}
+	}
+	return sent
+}
+
+// Failed returns the results of the messages which were not sent and
+// may be retried, vetoed messages are not included
+func (r BatchResult) Failed() []MessageResult {
+	failed := []MessageResult{}
+	for _, result := range r.Results {
+		if result.Status != StatusSent && result.Status != StatusVetoed {
+			failed = append(failed, result)
+		}
+	}
+	return failed
+}
+
+// Err returns the error of the first failed message, or nil if every
+// message was sent or vetoed
+func (r BatchResult) Err() error {
+	for i, result := range r.Results {
+		if result.Err != nil && result.Status != StatusSkipped {
+			return fmt.Errorf("mail: could not send email %d: %w", i+1, result.Err)
+		}
+	}
+	return nil
+}
+
+// deliverEachWith sends msgs through transport, one message at a time unless
+// the transport is a BatchTransport
+func deliverEachWith(transport Transport, sender Sender, mode SendMode, msgs []SendableMessage) []error {
+	if batch, ok := transport.(BatchTransport); ok {
+		return batch.DeliverEach(sender, mode, msgs...)
+	}
+	return sendEach(len(msgs), mode, func(i int) error {
+		return transport.Deliver(sender, msgs[i])
+	})
+}
+
+// sendEach calls send for each of the count messages and collects the
+// errors, in StopOnError mode the messages after a failure are not attempted
+func sendEach(count int, mode SendMode, send func(i int) error) []error {
+	errs := make([]error, count)
+	failed := false
+	for i := range errs {
+		if failed && mode == StopOnError {
+			errs[i] = ErrNotAttempted
+			continue
+		}
+		if errs[i] = send(i); errs[i] != nil {
+			failed = true
+		}
+	}
+	return errs
+}
+
+// statusOf classifies the error returned for a message
+func statusOf(err error) SendStatus {
+	if err == nil {
+		return StatusSent
+	}
+	if errors.Is(err, ErrNotAttempted) {
+		return StatusSkipped
+	}
+	var recipientErr RecipientError
+	if errors.As(err, &recipientErr) {
+		return StatusRejectedRecipient
+	}
+	// 550, 551 and 553 are the SMTP replies for mailboxes which are
+	// unavailable, not local or not allowed
+	var smtpErr *textproto.Error
+	if errors.As(err, &smtpErr) {
+		switch smtpErr.Code {
+		case 550, 551, 553:
+			return StatusRejectedRecipient
+		}
+	}
+	return StatusTransportError
+}
+
+// SendBatch sends the messages and reports the outcome of each of them.
+// Unlike Send, it can continue past failed messages so only the failed
+// ones need to be retried
+func (mailer *Mailer) SendBatch(mode SendMode, msgs ...SendableMessage) BatchResult {
+	results := make([]MessageResult, len(msgs))
+	outgoing := []SendableMessage{}
+	positions := []int{}
+	for i, msg := range msgs {
+		out, keep := mailer.applyBeforeSendHooks(msg)
+		if !keep {
+			results[i] = MessageResult{Message: msg, Status: StatusVetoed}
+			continue
+		}
+		outgoing = append(outgoing, out)
+		positions = append(positions, i)
+	}
+
+	if len(outgoing) > 0 {
+		errs := deliverEachWith(mailer.transport, mailer.sender(), mode, outgoing)
+		for j, err := range errs {
+			status := statusOf(err)
+			results[positions[j]] = MessageResult{Message: outgoing[j], Status: status, Err: err}
+			if status != StatusSkipped {
+				mailer.runAfterSendHooks(outgoing[j], err)
+			}
+		}
+	}
+	return BatchResult{Results: results}
+}
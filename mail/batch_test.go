@@ -0,0 +1,228 @@
+package mail
+
+import (
+	"errors"
+	"net"
+	"net/textproto"
+	"strings"
+	"sync/atomic"
+	"testing"
+
+	"github.com/stretchr/testify/suite"
+	"gopkg.in/gomail.v2"
+)
+
+type batchTestSuite struct {
+	suite.Suite
+	sendCloser *capturingSendCloser
+	mailer     *Mailer
+}
+
+func (s *batchTestSuite) SetupTest() {
+	s.sendCloser = &capturingSendCloser{reject: map[string]bool{"gone@me.com": true}}
+	transport := &SMTPTransport{dialer: &fakeSendDialer{sendCloser: s.sendCloser}}
+	s.mailer = NewTransportMailer("dummy", "root@dummy.com", transport)
+}
+
+func (s *batchTestSuite) message(recipient string) *dummyMessage {
+	msg := initializeMsg()
+	msg.txtMsg = "Hello there"
+	msg.recipients = append(msg.recipients, recipient)
+	return msg
+}
+
+func (s *batchTestSuite) TestContinueOnErrorSendsRemainingMessages() {
+	result := s.mailer.SendBatch(ContinueOnError,
+		s.message("a@me.com"), s.message("gone@me.com"), s.message("b@me.com"))
+
+	s.Len(s.sendCloser.sent, 2)
+	s.Equal(StatusSent, result.Results[0].Status)
+	s.Equal(StatusRejectedRecipient, result.Results[1].Status)
+	s.Equal(StatusSent, result.Results[2].Status)
+	s.Len(result.Sent(), 2)
+	s.Len(result.Failed(), 1)
+	s.Error(result.Err())
+}
+
+func (s *batchTestSuite) TestStopOnErrorSkipsRemainingMessages() {
+	result := s.mailer.SendBatch(StopOnError,
+		s.message("a@me.com"), s.message("gone@me.com"), s.message("b@me.com"))
+
+	s.Len(s.sendCloser.sent, 1)
+	s.Equal(StatusSkipped, result.Results[2].Status)
+	s.True(errors.Is(result.Results[2].Err, ErrNotAttempted))
+	s.Len(result.Failed(), 2)
+}
+
+func (s *batchTestSuite) TestInvalidAddressIsRejectedRecipient() {
+	result := s.mailer.SendBatch(ContinueOnError, s.message("not an address"))
+
+	s.Equal(StatusRejectedRecipient, result.Results[0].Status)
+}
+
+func (s *batchTestSuite) TestTransportErrorAndVetoStatuses() {
+	transport := &fakeTransport{}
+	mailer := NewTransportMailer("dummy", "root@dummy.com", transport)
+	mailer.AddBeforeSendHook(func(msg SendableMessage) (SendableMessage, bool) {
+		return msg, msg.GetRecipients()[0] != "vetoed@me.com"
+	})
+	failed := map[string]error{}
+	mailer.AddAfterSendHook(func(msg SendableMessage, err error) {
+		failed[msg.GetRecipients()[0]] = err
+	})
+	transport.err = errors.New("relay down")
+
+	result := mailer.SendBatch(ContinueOnError, s.message("vetoed@me.com"), s.message("a@me.com"))
+
+	s.Equal(StatusVetoed, result.Results[0].Status)
+	s.Equal(StatusTransportError, result.Results[1].Status)
+	s.Equal(transport.err, failed["a@me.com"])
+	s.NotContains(failed, "vetoed@me.com")
+}
+
+// countingDialer is an IDialer which refuses the messages sent to one
+// recipient and counts the connections it opens
+type countingDialer struct {
+	reject      string
+	connections int
+	sent        []*gomail.Message
+}
+
+func (d *countingDialer) DialAndSend(messages ...*gomail.Message) error {
+	d.connections++
+	for _, m := range messages {
+		if m.GetHeader("To")[0] == d.reject {
+			return &textproto.Error{Code: 550, Msg: "mailbox unavailable"}
+		}
+		d.sent = append(d.sent, m)
+	}
+	return nil
+}
+
+func (s *batchTestSuite) TestPlainDialerReportsTheErrorOfEachMessage() {
+	dialer := &countingDialer{reject: "gone@me.com"}
+	mailer := NewTransportMailer("dummy", "root@dummy.com", &SMTPTransport{dialer: dialer})
+	msgs := []SendableMessage{s.message("a@me.com"), s.message("gone@me.com"), s.message("b@me.com")}
+
+	continued := mailer.SendBatch(ContinueOnError, msgs...)
+	s.Equal([]SendStatus{StatusSent, StatusRejectedRecipient, StatusSent},
+		[]SendStatus{continued.Results[0].Status, continued.Results[1].Status, continued.Results[2].Status})
+	s.Len(dialer.sent, 2)
+
+	stopped := mailer.SendBatch(StopOnError, msgs...)
+	s.Equal(StatusSkipped, stopped.Results[2].Status)
+}
+
+// smtpServer is an SMTP server which keeps the transaction of a refused
+// recipient open, like real servers do, and refuses a nested MAIL
+type smtpServer struct {
+	listener    net.Listener
+	reject      string
+	connections int32
+	delivered   int32
+}
+
+func newSMTPServer(t *testing.T, reject string) *smtpServer {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	server := &smtpServer{listener: listener, reject: reject}
+	t.Cleanup(func() { listener.Close() })
+	go func() {
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+			atomic.AddInt32(&server.connections, 1)
+			go server.serve(conn)
+		}
+	}()
+	return server
+}
+
+func (srv *smtpServer) dialer() *gomail.Dialer {
+	addr := srv.listener.Addr().(*net.TCPAddr)
+	return gomail.NewDialer(addr.IP.String(), addr.Port, "", "")
+}
+
+func (srv *smtpServer) serve(conn net.Conn) {
+	defer conn.Close()
+	text := textproto.NewConn(conn)
+	text.PrintfLine("220 localhost ready")
+	inMail := false
+	for {
+		line, err := text.ReadLine()
+		if err != nil {
+			return
+		}
+		command := strings.ToUpper(line)
+		switch {
+		case strings.HasPrefix(command, "EHLO"), strings.HasPrefix(command, "HELO"):
+			text.PrintfLine("250 localhost")
+		case strings.HasPrefix(command, "MAIL"):
+			if inMail {
+				text.PrintfLine("503 nested MAIL command")
+				continue
+			}
+			inMail = true
+			text.PrintfLine("250 ok")
+		case strings.HasPrefix(command, "RCPT"):
+			if strings.Contains(line, srv.reject) {
+				text.PrintfLine("550 mailbox unavailable")
+				continue
+			}
+			text.PrintfLine("250 ok")
+		case command == "DATA":
+			text.PrintfLine("354 go ahead")
+			text.ReadDotLines()
+			inMail = false
+			atomic.AddInt32(&srv.delivered, 1)
+			text.PrintfLine("250 queued")
+		case command == "RSET":
+			inMail = false
+			text.PrintfLine("250 ok")
+		case command == "QUIT":
+			text.PrintfLine("221 bye")
+			return
+		default:
+			text.PrintfLine("502 not implemented")
+		}
+	}
+}
+
+func (s *batchTestSuite) TestSMTPBatchSharesOneConnection() {
+	server := newSMTPServer(s.T(), "gone@me.com")
+	mailer := NewTransportMailer("dummy", "root@dummy.com", &SMTPTransport{dialer: server.dialer()})
+
+	result := mailer.SendBatch(StopOnError, s.message("a@me.com"), s.message("b@me.com"), s.message("c@me.com"))
+
+	s.NoError(result.Err())
+	s.Equal(int32(3), atomic.LoadInt32(&server.delivered))
+	s.Equal(int32(1), atomic.LoadInt32(&server.connections))
+}
+
+func (s *batchTestSuite) TestSMTPBatchContinuesAfterRefusedRecipient() {
+	server := newSMTPServer(s.T(), "gone@me.com")
+	mailer := NewTransportMailer("dummy", "root@dummy.com", &SMTPTransport{dialer: server.dialer()})
+
+	result := mailer.SendBatch(ContinueOnError,
+		s.message("a@me.com"), s.message("gone@me.com"), s.message("b@me.com"), s.message("c@me.com"))
+
+	s.Equal([]SendStatus{StatusSent, StatusRejectedRecipient, StatusSent, StatusSent},
+		[]SendStatus{result.Results[0].Status, result.Results[1].Status, result.Results[2].Status, result.Results[3].Status})
+	s.Equal(int32(3), atomic.LoadInt32(&server.delivered))
+}
+
+func (s *batchTestSuite) TestSendErrorNamesTheFailedMessageOnce() {
+	err := s.mailer.Send(s.message("a@me.com"), s.message("gone@me.com"))
+
+	s.Require().Error(err)
+	s.Equal(1, strings.Count(err.Error(), "could not send email"))
+	s.Contains(err.Error(), "could not send email 2")
+}
+
+func TestSendBatch(t *testing.T) {
+	suite.Run(t, new(batchTestSuite))
+}
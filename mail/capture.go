@@ -0,0 +1,161 @@
+package mail
+
+import (
+	"html"
+	"regexp"
+	"strings"
+	"sync"
+)
+
+// CapturedMessage is a message recorded by a CapturingMailer
+type CapturedMessage struct {
+	Subject     string
+	Text        string
+	HTML        string
+	To          []string
+	Cc          []string
+	Bcc         []string
+	Headers     map[string][]string
+	Attachments []string
+	// Raw is the message rendered the same way it would be sent over SMTP
+	Raw string
+}
+
+// SentTo reports whether email is one of the recipients, cc or bcc
+// addresses of the message
+func (m CapturedMessage) SentTo(email string) bool {
+	for _, list := range [][]string{m.To, m.Cc, m.Bcc} {
+		for _, rcpt := range list {
+			if strings.EqualFold(rcpt, email) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// Links returns the links found in the html and text bodies of the message
+func (m CapturedMessage) Links() []string {
+	return ExtractLinks(m.HTML + "\n" + m.Text)
+}
+
+var linkPattern = regexp.MustCompile(`https?://[^\s"'<>]+`)
+
+// ExtractLinks returns the unique http and https links in body in the
+// order they first appear, html entities in the links are unescaped
+func ExtractLinks(body string) []string {
+	links := []string{}
+	seen := map[string]bool{}
+	for _, match := range linkPattern.FindAllString(body, -1) {
+		link := html.UnescapeString(strings.TrimRight(match, ".,;:!?)]"))
+		if !seen[link] {
+			seen[link] = true
+			links = append(links, link)
+		}
+	}
+	return links
+}
+
+// CapturingMailer is an implementation of the IMailer interface which keeps
+// sent messages in memory, it is meant for asserting on mails in tests
+type CapturingMailer struct {
+	sender   Sender
+	messages []CapturedMessage
+	mu       sync.Mutex
+}
+
+// NewCapturingMailer constructs an empty CapturingMailer
+func NewCapturingMailer() *CapturingMailer {
+	return &CapturingMailer{messages: []CapturedMessage{}}
+}
+
+// Send records the messages
+func (cm *CapturingMailer) Send(msgs ...SendableMessage) error {
+	cm.mu.Lock()
+	defer cm.mu.Unlock()
+	for _, msg := range msgs {
+		raw := new(strings.Builder)
+		buildMessage(cm.sender, msg).WriteTo(raw)
+		cm.messages = append(cm.messages, CapturedMessage{
+			Subject:     msg.GetSubject(),
+			Text:        msg.GetTextMessage(),
+			HTML:        msg.GetHTMLMessage(),
+			To:          copyStrings(msg.GetRecipients()),
+			Cc:          copyStrings(msg.GetCc()),
+			Bcc:         copyStrings(msg.GetBCc()),
+			Headers:     copyHeaders(msg.GetHeaders()),
+			Attachments: copyStrings(msg.GetAttachments()),
+			Raw:         raw.String(),
+		})
+	}
+	return nil
+}
+
+// copyStrings copies values so a message reused by the caller does not
+// change the captures made of it
+func copyStrings(values []string) []string {
+	if values == nil {
+		return nil
+	}
+	return append([]string{}, values...)
+}
+
+func copyHeaders(headers map[string][]string) map[string][]string {
+	if headers == nil {
+		return nil
+	}
+	copied := make(map[string][]string, len(headers))
+	for name, values := range headers {
+		copied[name] = copyStrings(values)
+	}
+	return copied
+}
+
+// Messages returns every message sent so far
+func (cm *CapturingMailer) Messages() []CapturedMessage {
+	cm.mu.Lock()
+	defer cm.mu.Unlock()
+	return append([]CapturedMessage{}, cm.messages...)
+}
+
+// Last returns the most recently sent message, ok is false if no
+// message has been sent
+func (cm *CapturingMailer) Last() (msg CapturedMessage, ok bool) {
+	cm.mu.Lock()
+	defer cm.mu.Unlock()
+	if len(cm.messages) == 0 {
+		return CapturedMessage{}, false
+	}
+	return cm.messages[len(cm.messages)-1], true
+}
+
+// FindByRecipient returns the messages sent to email
+func (cm *CapturingMailer) FindByRecipient(email string) []CapturedMessage {
+	return cm.filter(func(msg CapturedMessage) bool {
+		return msg.SentTo(email)
+	})
+}
+
+// FindBySubject returns the messages whose subject contains subject
+func (cm *CapturingMailer) FindBySubject(subject string) []CapturedMessage {
+	return cm.filter(func(msg CapturedMessage) bool {
+		return strings.Contains(msg.Subject, subject)
+	})
+}
+
+// Reset forgets every message sent so far
+func (cm *CapturingMailer) Reset() {
+	cm.mu.Lock()
+	defer cm.mu.Unlock()
+	cm.messages = []CapturedMessage{}
+}
+
+func (cm *CapturingMailer) filter(match func(CapturedMessage) bool) []CapturedMessage {
+	found := []CapturedMessage{}
+	for _, msg := range cm.Messages() {
+		if match(msg) {
+			found = append(found, msg)
+		}
+	}
+	return found
+}
@@ -0,0 +1,87 @@
+package mail
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/suite"
+)
+
+type testMailersTestSuite struct {
+	suite.Suite
+}
+
+func (s *testMailersTestSuite) message(recipient string, subject string) *dummyMessage {
+	msg := initializeMsg()
+	msg.subject = subject
+	msg.htmlMsg = `<a href="https://app.dummy.com/reset?token=abc&amp;uid=1">Reset</a>`
+	msg.txtMsg = "Reset your password at https://app.dummy.com/reset?token=abc&uid=1."
+	msg.recipients = append(msg.recipients, recipient)
+	return msg
+}
+
+func (s *testMailersTestSuite) TestCapturingMailerFindsMessages() {
+	mailer := NewCapturingMailer()
+
+	mailer.Send(s.message("me@me.com", "Reset your password"), s.message("you@me.com", "Welcome"))
+
+	s.Len(mailer.Messages(), 2)
+	found := mailer.FindByRecipient("ME@me.com")
+	s.Require().Len(found, 1)
+	s.Equal("Reset your password", found[0].Subject)
+	s.Len(mailer.FindBySubject("Welcome"), 1)
+	last, ok := mailer.Last()
+	s.True(ok)
+	s.True(last.SentTo("you@me.com"))
+	s.Contains(last.Raw, "Subject: Welcome")
+
+	mailer.Reset()
+	s.Empty(mailer.Messages())
+}
+
+func (s *testMailersTestSuite) TestCapturedMessageExtractsLinks() {
+	mailer := NewCapturingMailer()
+	mailer.Send(s.message("me@me.com", "Reset your password"))
+
+	last, _ := mailer.Last()
+
+	s.Equal([]string{"https://app.dummy.com/reset?token=abc&uid=1"}, last.Links())
+}
+
+func (s *testMailersTestSuite) TestCapturedMessageIsNotChangedByReuse() {
+	mailer := NewCapturingMailer()
+	msg := s.message("me@me.com", "Reset your password")
+	msg.headers["X-Campaign"] = []string{"reset"}
+	mailer.Send(msg)
+
+	msg.recipients[0] = "you@me.com"
+	msg.headers["X-Campaign"][0] = "welcome"
+	msg.headers["X-Other"] = []string{"other"}
+	mailer.Send(msg)
+
+	first := mailer.Messages()[0]
+	s.Equal([]string{"me@me.com"}, first.To)
+	s.Equal(map[string][]string{"X-Campaign": {"reset"}}, first.Headers)
+}
+
+func (s *testMailersTestSuite) TestFileMailerWritesMaildir() {
+	dir := s.T().TempDir()
+	mailer, err := NewFileMailer("dummy", "root@dummy.com", dir)
+	s.Require().NoError(err)
+
+	s.Require().NoError(mailer.Send(s.message("me@me.com", "First"), s.message("me@me.com", "Second")))
+
+	files, err := filepath.Glob(filepath.Join(mailer.Dir(), "*.eml"))
+	s.Require().NoError(err)
+	s.Len(files, 2)
+	tmpFiles, _ := os.ReadDir(filepath.Join(dir, "tmp"))
+	s.Empty(tmpFiles)
+	content, err := os.ReadFile(files[0])
+	s.Require().NoError(err)
+	s.Contains(string(content), "root@dummy.com")
+}
+
+func TestTestMailers(t *testing.T) {
+	suite.Run(t, new(testMailersTestSuite))
+}
@@ -0,0 +1,245 @@
+package mail
+
+import (
+	"fmt"
+	"sync"
+	"time"
+)
+
+// Strategy decides which transport a CompositeMailer tries first
+type Strategy int
+
+const (
+	// Failover always tries the transports in the order they were registered
+	Failover Strategy = iota
+	// RoundRobin rotates the first transport tried between messages
+	RoundRobin
+	// Weighted distributes the first transport tried in proportion to the
+	// weight of each transport
+	Weighted
+)
+
+const (
+	defaultFailureThreshold  = 3
+	defaultUnhealthyCooldown = 30 * time.Second
+)
+
+// NamedTransport is a transport registered with a CompositeMailer,
+// the name is used in delivery reports. Weight is only used by the
+// Weighted strategy, a weight below 1 is treated as 1
+type NamedTransport struct {
+	Name      string
+	Transport Transport
+	Weight    int
+}
+
+// Attempt is a single try at delivering a message through a transport
+type Attempt struct {
+	Transport string
+	Err       error
+}
+
+// Delivery reports how a message was sent by a CompositeMailer.
+// Transport is the name of the transport which delivered the message,
+// it is empty when every transport failed
+type Delivery struct {
+	Message   SendableMessage
+	Transport string
+	Attempts  []Attempt
+}
+
+// Delivered reports whether any transport accepted the message
+func (d Delivery) Delivered() bool {
+	return d.Transport != ""
+}
+
+// Err returns the error of the last failed attempt if the message
+// was not delivered
+func (d Delivery) Err() error {
+	if d.Delivered() || len(d.Attempts) == 0 {
+		return nil
+	}
+	return d.Attempts[len(d.Attempts)-1].Err
+}
+
+type transportState struct {
+	NamedTransport
+	currentWeight  int
+	failures       int
+	unhealthyUntil time.Time
+}
+
+// CompositeMailer is an IMailer which sends through several transports,
+// when a transport fails the next one is tried. A transport which fails
+// failureThreshold times in a row is marked unhealthy and is only tried
+// after the healthy ones until the cooldown elapses
+type CompositeMailer struct {
+	sender           Sender
+	strategy         Strategy
+	transports       []*transportState
+	failureThreshold int
+	cooldown         time.Duration
+	next             int
+	now              func() time.Time
+	mu               sync.Mutex
+}
+
+// NewCompositeMailer constructs a CompositeMailer sending from the given
+// sender through transports using strategy
+func NewCompositeMailer(
+	sendername string, senderemail string,
+	strategy Strategy, transports ...NamedTransport) *CompositeMailer {
+	states := []*transportState{}
+	for _, transport := range transports {
+		if transport.Weight < 1 {
+			transport.Weight = 1
+		}
+		states = append(states, &transportState{NamedTransport: transport})
+	}
+	return &CompositeMailer{
+		sender:           Sender{Name: sendername, Email: senderemail},
+		strategy:         strategy,
+		transports:       states,
+		failureThreshold: defaultFailureThreshold,
+		cooldown:         defaultUnhealthyCooldown,
+		now:              time.Now,
+	}
+}
+
+// SetHealthPolicy sets how many consecutive failures mark a transport
+// unhealthy and how long it stays unhealthy
+func (m *CompositeMailer) SetHealthPolicy(failureThreshold int, cooldown time.Duration) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.failureThreshold = failureThreshold
+	m.cooldown = cooldown
+}
+
+// Healthy reports whether the named transport is currently healthy
+func (m *CompositeMailer) Healthy(name string) bool {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	for _, state := range m.transports {
+		if state.Name == name {
+			return m.isHealthy(state)
+		}
+	}
+	return false
+}
+
+// Send sends every message, it returns an error if any message could
+// not be delivered by any of the transports
+func (m *CompositeMailer) Send(msgs ...SendableMessage) error {
+	_, err := m.SendWithReport(msgs...)
+	return err
+}
+
+// SendWithReport sends every message and reports which transport
+// delivered each of them
+func (m *CompositeMailer) SendWithReport(msgs ...SendableMessage) ([]Delivery, error) {
+	deliveries := []Delivery{}
+	failed := 0
+	var firstErr error
+	for _, msg := range msgs {
+		delivery := m.deliver(msg)
+		if !delivery.Delivered() {
+			failed++
+			if firstErr == nil {
+				firstErr = delivery.Err()
+			}
+		}
+		deliveries = append(deliveries, delivery)
+	}
+	if failed > 0 {
+		return deliveries, fmt.Errorf(
+			"mail: %d of %d messages could not be delivered: %w", failed, len(msgs), firstErr)
+	}
+	return deliveries, nil
+}
+
+func (m *CompositeMailer) deliver(msg SendableMessage) Delivery {
+	delivery := Delivery{Message: msg, Attempts: []Attempt{}}
+	for _, state := range m.order() {
+		err := state.Transport.Deliver(m.sender, msg)
+		m.record(state, err)
+		delivery.Attempts = append(delivery.Attempts, Attempt{Transport: state.Name, Err: err})
+		if err == nil {
+			delivery.Transport = state.Name
+			break
+		}
+	}
+	if len(delivery.Attempts) == 0 {
+		delivery.Attempts = append(delivery.Attempts, Attempt{
+			Err: fmt.Errorf("mail: no transports registered")})
+	}
+	return delivery
+}
+
+// order returns the transports in the order they should be tried for the
+// next message, healthy transports come before unhealthy ones
+func (m *CompositeMailer) order() []*transportState {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if len(m.transports) == 0 {
+		return nil
+	}
+
+	first := 0
+	switch m.strategy {
+	case RoundRobin:
+		first = m.next % len(m.transports)
+		m.next++
+	case Weighted:
+		first = m.pickWeighted()
+	}
+
+	ordered := []*transportState{}
+	for i := range m.transports {
+		ordered = append(ordered, m.transports[(first+i)%len(m.transports)])
+	}
+
+	healthy := []*transportState{}
+	unhealthy := []*transportState{}
+	for _, state := range ordered {
+		if m.isHealthy(state) {
+			healthy = append(healthy, state)
+		} else {
+			unhealthy = append(unhealthy, state)
+		}
+	}
+	return append(healthy, unhealthy...)
+}
+
+// pickWeighted implements smooth weighted round robin, which spreads the
+// picks of each transport evenly instead of in bursts
+func (m *CompositeMailer) pickWeighted() int {
+	total := 0
+	best := 0
+	for i, state := range m.transports {
+		state.currentWeight += state.Weight
+		total += state.Weight
+		if state.currentWeight > m.transports[best].currentWeight {
+			best = i
+		}
+	}
+	m.transports[best].currentWeight -= total
+	return best
+}
+
+func (m *CompositeMailer) isHealthy(state *transportState) bool {
+	return !m.now().Before(state.unhealthyUntil)
+}
+
+func (m *CompositeMailer) record(state *transportState, err error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if err == nil {
+		state.failures = 0
+		state.unhealthyUntil = time.Time{}
+		return
+	}
+	state.failures++
+	if state.failures >= m.failureThreshold {
+		state.unhealthyUntil = m.now().Add(m.cooldown)
+	}
+}
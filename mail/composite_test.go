@@ -0,0 +1,123 @@
+package mail
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/suite"
+)
+
+type fakeTransport struct {
+	err       error
+	delivered []SendableMessage
+}
+
+func (t *fakeTransport) Deliver(sender Sender, msgs ...SendableMessage) error {
+	if t.err != nil {
+		return t.err
+	}
+	t.delivered = append(t.delivered, msgs...)
+	return nil
+}
+
+type compositeMailerTestSuite struct {
+	suite.Suite
+	primary   *fakeTransport
+	secondary *fakeTransport
+	now       time.Time
+}
+
+func (s *compositeMailerTestSuite) SetupTest() {
+	s.primary = &fakeTransport{}
+	s.secondary = &fakeTransport{}
+	s.now = time.Now()
+}
+
+func (s *compositeMailerTestSuite) mailer(strategy Strategy, primaryWeight int) *CompositeMailer {
+	mailer := NewCompositeMailer("dummy", "root@dummy.com", strategy,
+		NamedTransport{Name: "primary", Transport: s.primary, Weight: primaryWeight},
+		NamedTransport{Name: "secondary", Transport: s.secondary, Weight: 1},
+	)
+	mailer.now = func() time.Time { return s.now }
+	return mailer
+}
+
+func (s *compositeMailerTestSuite) message() *dummyMessage {
+	msg := initializeMsg()
+	msg.txtMsg = "Hello there"
+	msg.recipients = append(msg.recipients, "me@me.com")
+	return msg
+}
+
+func (s *compositeMailerTestSuite) TestFailoverUsesSecondaryWhenPrimaryFails() {
+	s.primary.err = errors.New("relay down")
+	mailer := s.mailer(Failover, 1)
+
+	deliveries, err := mailer.SendWithReport(s.message())
+
+	s.NoError(err)
+	s.Equal("secondary", deliveries[0].Transport)
+	s.Len(deliveries[0].Attempts, 2)
+	s.Len(s.secondary.delivered, 1)
+}
+
+func (s *compositeMailerTestSuite) TestRoundRobinAlternatesTransports() {
+	mailer := s.mailer(RoundRobin, 1)
+
+	deliveries, err := mailer.SendWithReport(s.message(), s.message(), s.message(), s.message())
+
+	s.NoError(err)
+	s.Equal("primary", deliveries[0].Transport)
+	s.Equal("secondary", deliveries[1].Transport)
+	s.Len(s.primary.delivered, 2)
+	s.Len(s.secondary.delivered, 2)
+}
+
+func (s *compositeMailerTestSuite) TestWeightedDistributesByWeight() {
+	mailer := s.mailer(Weighted, 3)
+	msgs := []SendableMessage{}
+	for i := 0; i < 8; i++ {
+		msgs = append(msgs, s.message())
+	}
+
+	s.NoError(mailer.Send(msgs...))
+
+	s.Len(s.primary.delivered, 6)
+	s.Len(s.secondary.delivered, 2)
+}
+
+func (s *compositeMailerTestSuite) TestTransportMarkedUnhealthyAfterConsecutiveFailures() {
+	s.primary.err = errors.New("relay down")
+	mailer := s.mailer(Failover, 1)
+	mailer.SetHealthPolicy(2, time.Minute)
+
+	mailer.Send(s.message(), s.message())
+	s.False(mailer.Healthy("primary"))
+
+	deliveries, _ := mailer.SendWithReport(s.message())
+	s.Equal("secondary", deliveries[0].Attempts[0].Transport)
+	s.Len(deliveries[0].Attempts, 1)
+
+	s.primary.err = nil
+	s.now = s.now.Add(2 * time.Minute)
+	s.True(mailer.Healthy("primary"))
+	deliveries, _ = mailer.SendWithReport(s.message())
+	s.Equal("primary", deliveries[0].Transport)
+}
+
+func (s *compositeMailerTestSuite) TestErrorWhenAllTransportsFail() {
+	s.primary.err = errors.New("relay down")
+	s.secondary.err = errors.New("api down")
+	mailer := s.mailer(Failover, 1)
+
+	deliveries, err := mailer.SendWithReport(s.message())
+
+	s.Error(err)
+	s.False(deliveries[0].Delivered())
+	s.Equal(s.secondary.err, deliveries[0].Err())
+}
+
+func TestCompositeMailer(t *testing.T) {
+	suite.Run(t, new(compositeMailerTestSuite))
+}
@@ -0,0 +1,134 @@
+package mail
+
+import (
+	"bytes"
+	"crypto"
+	"crypto/ed25519"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"io"
+	"net/mail"
+
+	"github.com/emersion/go-msgauth/dkim"
+	"gopkg.in/gomail.v2"
+)
+
+// DefaultDKIMHeaders are the header fields covered by a DKIM signature
+// when no header fields are given to NewDKIMSigner
+var DefaultDKIMHeaders = []string{
+	"From", "To", "Cc", "Subject", "Date", "Mime-Version", "Content-Type",
+	"Reply-To", "List-Unsubscribe", "List-Unsubscribe-Post",
+}
+
+// DKIMSigner adds a DKIM-Signature header to outgoing mail
+type DKIMSigner struct {
+	options *dkim.SignOptions
+}
+
+// NewDKIMSigner constructs a DKIMSigner for domain and selector.
+// privateKey is a PEM encoded RSA (PKCS #1 or PKCS #8) or Ed25519 (PKCS #8)
+// private key, RSA keys sign with rsa-sha256 and Ed25519 keys with
+// ed25519-sha256
+func NewDKIMSigner(domain string, selector string, privateKey []byte, headers ...string) (*DKIMSigner, error) {
+	signer, err := ParseDKIMPrivateKey(privateKey)
+	if err != nil {
+		return nil, err
+	}
+	if len(headers) == 0 {
+		headers = DefaultDKIMHeaders
+	}
+	return &DKIMSigner{options: &dkim.SignOptions{
+		Domain:                 domain,
+		Selector:               selector,
+		Signer:                 signer,
+		Hash:                   crypto.SHA256,
+		HeaderCanonicalization: dkim.CanonicalizationRelaxed,
+		BodyCanonicalization:   dkim.CanonicalizationRelaxed,
+		HeaderKeys:             headers,
+	}}, nil
+}
+
+// ParseDKIMPrivateKey parses a PEM encoded RSA or Ed25519 private key
+func ParseDKIMPrivateKey(privateKey []byte) (crypto.Signer, error) {
+	block, _ := pem.Decode(privateKey)
+	if block == nil {
+		return nil, errors.New("mail: DKIM private key is not PEM encoded")
+	}
+	if block.Type == "RSA PRIVATE KEY" {
+		return x509.ParsePKCS1PrivateKey(block.Bytes)
+	}
+	key, err := x509.ParsePKCS8PrivateKey(block.Bytes)
+	if err != nil {
+		return nil, fmt.Errorf("mail: invalid DKIM private key: %w", err)
+	}
+	switch key := key.(type) {
+	case *rsa.PrivateKey:
+		return key, nil
+	case ed25519.PrivateKey:
+		return key, nil
+	}
+	return nil, errors.New("mail: DKIM private key must be an RSA or Ed25519 key")
+}
+
+// Sign renders m and returns the rendered message with a DKIM-Signature
+// header prepended. The rendered message must be sent as is, rendering m
+// again changes its multipart boundaries and invalidates the signature
+func (s *DKIMSigner) Sign(m *gomail.Message) ([]byte, error) {
+	raw := new(bytes.Buffer)
+	if _, err := m.WriteTo(raw); err != nil {
+		return nil, err
+	}
+	signed := new(bytes.Buffer)
+	if err := dkim.Sign(signed, bytes.NewReader(raw.Bytes()), s.options); err != nil {
+		return nil, err
+	}
+	return signed.Bytes(), nil
+}
+
+// rawMessage sends prerendered bytes through a gomail.Sender
+type rawMessage []byte
+
+func (m rawMessage) WriteTo(w io.Writer) (int64, error) {
+	n, err := w.Write(m)
+	return int64(n), err
+}
+
+// envelope returns the SMTP envelope sender and recipients of m
+// the same way gomail.Send does
+func envelope(m *gomail.Message) (from string, to []string, err error) {
+	fromHeader := m.GetHeader("Sender")
+	if len(fromHeader) == 0 {
+		fromHeader = m.GetHeader("From")
+	}
+	if len(fromHeader) == 0 {
+		return "", nil, errors.New(`mail: invalid message, "From" field is absent`)
+	}
+	if from, err = parseAddress(fromHeader[0]); err != nil {
+		return "", nil, err
+	}
+	seen := map[string]bool{}
+	for _, field := range []string{"To", "Cc", "Bcc"} {
+		for _, value := range m.GetHeader(field) {
+			addr, err := parseAddress(value)
+			if err != nil {
+				return "", nil, RecipientError{Address: value, Err: err}
+			}
+			if !seen[addr] {
+				seen[addr] = true
+				to = append(to, addr)
+			}
+		}
+	}
+	return from, to, nil
+}
+
+func parseAddress(field string) (string, error) {
+	addr, err := mail.ParseAddress(field)
+	if err != nil {
+		return "", fmt.Errorf("mail: invalid address %q: %w", field, err)
+	}
+	return addr.Address, nil
+}
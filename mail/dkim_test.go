@@ -0,0 +1,138 @@
+package mail
+
+import (
+	"bytes"
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/pem"
+	"io"
+	"net/textproto"
+	"testing"
+
+	"github.com/emersion/go-msgauth/dkim"
+	"github.com/stretchr/testify/suite"
+	"gopkg.in/gomail.v2"
+)
+
+type capturingSendCloser struct {
+	sent   [][]byte
+	reject map[string]bool
+}
+
+func (sc *capturingSendCloser) Send(from string, to []string, msg io.WriterTo) error {
+	for _, rcpt := range to {
+		if sc.reject[rcpt] {
+			return &textproto.Error{Code: 550, Msg: "mailbox unavailable"}
+		}
+	}
+	buf := new(bytes.Buffer)
+	msg.WriteTo(buf)
+	sc.sent = append(sc.sent, buf.Bytes())
+	return nil
+}
+
+func (sc *capturingSendCloser) Close() error {
+	return nil
+}
+
+type fakeSendDialer struct {
+	sendCloser *capturingSendCloser
+}
+
+func (d *fakeSendDialer) DialAndSend(msgs ...*gomail.Message) error {
+	return gomail.Send(d.sendCloser, msgs...)
+}
+
+func (d *fakeSendDialer) Dial() (gomail.SendCloser, error) {
+	return d.sendCloser, nil
+}
+
+type dkimTestSuite struct {
+	suite.Suite
+	dialer *fakeSendDialer
+	sender Sender
+}
+
+func (s *dkimTestSuite) SetupTest() {
+	s.dialer = &fakeSendDialer{sendCloser: &capturingSendCloser{}}
+	s.sender = Sender{Name: "dummy", Email: "root@dummy.com"}
+}
+
+func (s *dkimTestSuite) message() *dummyMessage {
+	msg := initializeMsg()
+	msg.subject = "Signed"
+	msg.txtMsg = "This is a text message"
+	msg.htmlMsg = "<h1>Hello I am me</h1>"
+	msg.recipients = append(msg.recipients, "me@me.com")
+	return msg
+}
+
+func (s *dkimTestSuite) sendAndVerify(privateKey []byte, txtRecord string) {
+	signer, err := NewDKIMSigner("dummy.com", "mail", privateKey)
+	s.Require().NoError(err)
+	transport := &SMTPTransport{dialer: s.dialer}
+	transport.SetDKIMSigner(signer)
+
+	s.Require().NoError(transport.Deliver(s.sender, s.message()))
+
+	s.Require().Len(s.dialer.sendCloser.sent, 1)
+	verifications, err := dkim.VerifyWithOptions(
+		bytes.NewReader(s.dialer.sendCloser.sent[0]),
+		&dkim.VerifyOptions{LookupTXT: func(domain string) ([]string, error) {
+			s.Equal("mail._domainkey.dummy.com", domain)
+			return []string{txtRecord}, nil
+		}})
+	s.Require().NoError(err)
+	s.Require().Len(verifications, 1)
+	s.NoError(verifications[0].Err)
+	s.Equal("dummy.com", verifications[0].Domain)
+}
+
+func (s *dkimTestSuite) TestRSASignatureVerifies() {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	s.Require().NoError(err)
+	privateKey := pem.EncodeToMemory(&pem.Block{
+		Type: "RSA PRIVATE KEY", Bytes: x509.MarshalPKCS1PrivateKey(key)})
+	publicKey, _ := x509.MarshalPKIXPublicKey(&key.PublicKey)
+
+	s.sendAndVerify(privateKey, "v=DKIM1; k=rsa; p="+base64.StdEncoding.EncodeToString(publicKey))
+}
+
+func (s *dkimTestSuite) TestEd25519SignatureVerifies() {
+	publicKey, key, err := ed25519.GenerateKey(rand.Reader)
+	s.Require().NoError(err)
+	der, _ := x509.MarshalPKCS8PrivateKey(key)
+	privateKey := pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: der})
+
+	s.sendAndVerify(privateKey, "v=DKIM1; k=ed25519; p="+base64.StdEncoding.EncodeToString(publicKey))
+}
+
+func (s *dkimTestSuite) TestTamperedMessageFailsVerification() {
+	publicKey, key, _ := ed25519.GenerateKey(rand.Reader)
+	der, _ := x509.MarshalPKCS8PrivateKey(key)
+	signer, err := NewDKIMSigner("dummy.com", "mail", pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: der}))
+	s.Require().NoError(err)
+	transport := &SMTPTransport{dialer: s.dialer}
+	transport.SetDKIMSigner(signer)
+	transport.Deliver(s.sender, s.message())
+
+	tampered := bytes.Replace(s.dialer.sendCloser.sent[0], []byte("Subject: Signed"), []byte("Subject: Forged"), 1)
+	verifications, err := dkim.VerifyWithOptions(bytes.NewReader(tampered),
+		&dkim.VerifyOptions{LookupTXT: func(domain string) ([]string, error) {
+			return []string{"v=DKIM1; k=ed25519; p=" + base64.StdEncoding.EncodeToString(publicKey)}, nil
+		}})
+	s.Require().NoError(err)
+	s.Error(verifications[0].Err)
+}
+
+func (s *dkimTestSuite) TestInvalidPrivateKey() {
+	_, err := NewDKIMSigner("dummy.com", "mail", []byte("not a key"))
+	s.Error(err)
+}
+
+func TestDKIM(t *testing.T) {
+	suite.Run(t, new(dkimTestSuite))
+}
@@ -0,0 +1,73 @@
+package mail
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync/atomic"
+	"time"
+)
+
+// FileMailer is an implementation of the IMailer interface which writes
+// every mail as an .eml file into a maildir style directory. Mails are
+// written into dir/tmp and moved into dir/new once complete, so a reader
+// watching dir/new never sees a partially written mail
+type FileMailer struct {
+	sender  Sender
+	dir     string
+	counter uint64
+}
+
+// NewFileMailer constructs a FileMailer writing into dir, the tmp, new
+// and cur subdirectories are created if they do not exist
+func NewFileMailer(sendername string, senderemail string, dir string) (*FileMailer, error) {
+	for _, sub := range []string{"tmp", "new", "cur"} {
+		if err := os.MkdirAll(filepath.Join(dir, sub), 0755); err != nil {
+			return nil, err
+		}
+	}
+	return &FileMailer{
+		sender: Sender{Name: sendername, Email: senderemail},
+		dir:    dir,
+	}, nil
+}
+
+// Dir returns the directory new mails are delivered into
+func (fm *FileMailer) Dir() string {
+	return filepath.Join(fm.dir, "new")
+}
+
+// Send builds emails from the provided messages and writes each
+// email to its own file
+func (fm *FileMailer) Send(msgs ...SendableMessage) error {
+	for _, msg := range buildMessages(fm.sender, msgs...) {
+		name := fm.uniqueName()
+		tmpPath := filepath.Join(fm.dir, "tmp", name)
+		file, err := os.Create(tmpPath)
+		if err != nil {
+			return err
+		}
+		if _, err := msg.WriteTo(file); err != nil {
+			file.Close()
+			os.Remove(tmpPath)
+			return err
+		}
+		if err := file.Close(); err != nil {
+			os.Remove(tmpPath)
+			return err
+		}
+		if err := os.Rename(tmpPath, filepath.Join(fm.dir, "new", name)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (fm *FileMailer) uniqueName() string {
+	count := atomic.AddUint64(&fm.counter, 1)
+	hostname, err := os.Hostname()
+	if err != nil {
+		hostname = "localhost"
+	}
+	return fmt.Sprintf("%d.P%dQ%d.%s.eml", time.Now().UnixNano(), os.Getpid(), count, hostname)
+}
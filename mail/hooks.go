@@ -0,0 +1,91 @@
+package mail
+
+import (
+	"strings"
+
+	"github.com/dino16m/golearn-core/bus"
+	"github.com/dino16m/golearn-core/event"
+)
+
+// BeforeSendHook is called with every message before it is sent.
+// It returns the message to send, which may be a modified copy of msg,
+// or false to veto the message so it is silently dropped
+type BeforeSendHook func(msg SendableMessage) (SendableMessage, bool)
+
+// AfterSendHook is called with every message after it was sent,
+// err is nil if the message was sent
+type AfterSendHook func(msg SendableMessage, err error)
+
+// AddBeforeSendHook registers a hook run before messages are sent,
+// hooks run in the order they were added
+func (mailer *Mailer) AddBeforeSendHook(hook BeforeSendHook) {
+	mailer.beforeSend = append(mailer.beforeSend, hook)
+}
+
+// AddAfterSendHook registers a hook run after messages are sent,
+// hooks run in the order they were added
+func (mailer *Mailer) AddAfterSendHook(hook AfterSendHook) {
+	mailer.afterSend = append(mailer.afterSend, hook)
+}
+
+// PublishEvents dispatches an event.MailSent or event.MailFailed
+// on eventBus for every message the mailer sends
+func (mailer *Mailer) PublishEvents(eventBus *bus.EventBus) {
+	mailer.AddAfterSendHook(func(msg SendableMessage, err error) {
+		if err != nil {
+			eventBus.Dispatch(event.NewMailFailedEvent(msg, err))
+		} else {
+			eventBus.Dispatch(event.NewMailSentEvent(msg))
+		}
+	})
+}
+
+func (mailer *Mailer) applyBeforeSendHooks(msg SendableMessage) (SendableMessage, bool) {
+	keep := true
+	for _, hook := range mailer.beforeSend {
+		if msg, keep = hook(msg); !keep {
+			return nil, false
+		}
+	}
+	return msg, true
+}
+
+func (mailer *Mailer) runAfterSendHooks(msg SendableMessage, err error) {
+	for _, hook := range mailer.afterSend {
+		hook(msg, err)
+	}
+}
+
+// CopyMessage returns a Message holding a copy of the content of msg,
+// hooks use it to modify messages without touching the original
+func CopyMessage(msg SendableMessage) *Message {
+	headers := make(map[string][]string)
+	for key, value := range msg.GetHeaders() {
+		headers[key] = append([]string{}, value...)
+	}
+	return &Message{
+		Subject:     msg.GetSubject(),
+		HTMLMsg:     msg.GetHTMLMessage(),
+		TxtMsg:      msg.GetTextMessage(),
+		Attachments: append([]string{}, msg.GetAttachments()...),
+		Headers:     headers,
+		Cc:          append([]string{}, msg.GetCc()...),
+		Bcc:         append([]string{}, msg.GetBCc()...),
+		Recipients:  append([]string{}, msg.GetRecipients()...),
+	}
+}
+
+// RedirectTo returns a hook which sends every message to sink instead of
+// its recipients, e.g. to keep a staging environment from mailing real
+// users. The original recipients are kept in the X-Original-To header
+func RedirectTo(sink string) BeforeSendHook {
+	return func(msg SendableMessage) (SendableMessage, bool) {
+		redirected := CopyMessage(msg)
+		original := append(append(redirected.Recipients, redirected.Cc...), redirected.Bcc...)
+		redirected.Headers["X-Original-To"] = []string{strings.Join(original, ", ")}
+		redirected.Recipients = []string{sink}
+		redirected.Cc = []string{}
+		redirected.Bcc = []string{}
+		return redirected, true
+	}
+}
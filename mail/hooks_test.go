@@ -0,0 +1,116 @@
+package mail
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/dino16m/golearn-core/bus"
+	"github.com/dino16m/golearn-core/event"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/suite"
+)
+
+type hooksTestSuite struct {
+	suite.Suite
+	transport *fakeTransport
+	mailer    *Mailer
+}
+
+func (s *hooksTestSuite) SetupTest() {
+	s.transport = &fakeTransport{}
+	s.mailer = NewTransportMailer("dummy", "root@dummy.com", s.transport)
+}
+
+func (s *hooksTestSuite) message(recipient string) *dummyMessage {
+	msg := initializeMsg()
+	msg.subject = "Hello"
+	msg.txtMsg = "Hello there"
+	msg.recipients = append(msg.recipients, recipient)
+	msg.cc = append(msg.cc, "cc@me.com")
+	return msg
+}
+
+func (s *hooksTestSuite) TestRedirectToSink() {
+	s.mailer.AddBeforeSendHook(RedirectTo("sink@dummy.com"))
+	msg := s.message("me@me.com")
+
+	s.NoError(s.mailer.Send(msg))
+
+	s.Require().Len(s.transport.delivered, 1)
+	sent := s.transport.delivered[0]
+	s.Equal([]string{"sink@dummy.com"}, sent.GetRecipients())
+	s.Empty(sent.GetCc())
+	s.Equal([]string{"me@me.com, cc@me.com"}, sent.GetHeaders()["X-Original-To"])
+	s.Equal([]string{"me@me.com"}, msg.GetRecipients())
+}
+
+func (s *hooksTestSuite) TestVetoDropsMessage() {
+	s.mailer.AddBeforeSendHook(func(msg SendableMessage) (SendableMessage, bool) {
+		return msg, msg.GetRecipients()[0] != "blocked@me.com"
+	})
+	sent := []SendableMessage{}
+	s.mailer.AddAfterSendHook(func(msg SendableMessage, err error) {
+		sent = append(sent, msg)
+	})
+
+	s.NoError(s.mailer.Send(s.message("blocked@me.com"), s.message("me@me.com")))
+
+	s.Len(s.transport.delivered, 1)
+	s.Require().Len(sent, 1)
+	s.Equal([]string{"me@me.com"}, sent[0].GetRecipients())
+}
+
+func (s *hooksTestSuite) TestPublishesMailEvents() {
+	eventBus := bus.NewEventBus()
+	sentListener := bus.NewMockListener(s.T())
+	failedListener := bus.NewMockListener(s.T())
+	eventBus.AddListener(event.MailSent{}, sentListener)
+	eventBus.AddListener(event.MailFailed{}, failedListener)
+	sentListener.On("Handle", mock.Anything)
+	failedListener.On("Handle", mock.Anything)
+	s.mailer.PublishEvents(eventBus)
+	msg := s.message("me@me.com")
+
+	s.mailer.Send(msg)
+	s.transport.err = errors.New("relay down")
+	s.mailer.Send(msg)
+
+	sentListener.AssertCalled(s.T(), "Handle", event.NewMailSentEvent(msg))
+	failedListener.AssertCalled(s.T(), "Handle", event.NewMailFailedEvent(msg, s.transport.err))
+}
+
+// failingRecipientTransport fails the messages sent to one recipient
+type failingRecipientTransport struct {
+	fakeTransport
+	recipient string
+}
+
+func (t *failingRecipientTransport) Deliver(sender Sender, msgs ...SendableMessage) error {
+	for _, msg := range msgs {
+		if msg.GetRecipients()[0] == t.recipient {
+			return errors.New("mailbox unavailable")
+		}
+	}
+	return t.fakeTransport.Deliver(sender, msgs...)
+}
+
+func (s *hooksTestSuite) TestAfterSendHooksGetTheErrorOfTheirMessage() {
+	transport := &failingRecipientTransport{recipient: "bad@me.com"}
+	mailer := NewTransportMailer("dummy", "root@dummy.com", transport)
+	results := map[string]error{}
+	mailer.AddAfterSendHook(func(msg SendableMessage, err error) {
+		results[msg.GetRecipients()[0]] = err
+	})
+
+	err := mailer.Send(s.message("good@me.com"), s.message("bad@me.com"), s.message("later@me.com"))
+
+	s.Error(err)
+	s.Require().Len(results, 2)
+	s.NoError(results["good@me.com"])
+	s.ErrorIs(err, results["bad@me.com"])
+	s.Len(transport.delivered, 1)
+}
+
+func TestMailerHooks(t *testing.T) {
+	suite.Run(t, new(hooksTestSuite))
+}
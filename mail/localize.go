@@ -0,0 +1,204 @@
+package mail
+
+import (
+	"bytes"
+	"fmt"
+	htmltemplate "html/template"
+	"strings"
+	"sync"
+	"text/template"
+)
+
+// Template is the source of a mail template in one locale. Subject and Text
+// are parsed with text/template and HTML with html/template
+type Template struct {
+	Subject string
+	Text    string
+	HTML    string
+}
+
+type compiledTemplate struct {
+	subject *template.Template
+	text    *template.Template
+	html    *htmltemplate.Template
+}
+
+// TemplateCatalog holds the translations of mail templates. Locales are
+// resolved with a fallback chain, e.g. fr-CA falls back to fr and then to
+// the default locale of the catalog
+type TemplateCatalog struct {
+	defaultLocale string
+	templates     map[string]map[string]*compiledTemplate
+	mu            sync.RWMutex
+}
+
+// NewTemplateCatalog constructs an empty catalog
+func NewTemplateCatalog(defaultLocale string) *TemplateCatalog {
+	return &TemplateCatalog{
+		defaultLocale: normalizeLocale(defaultLocale),
+		templates:     make(map[string]map[string]*compiledTemplate),
+	}
+}
+
+// Register parses and adds the translation of the named template for locale
+func (c *TemplateCatalog) Register(name string, locale string, tmpl Template) error {
+	compiled := &compiledTemplate{}
+	var err error
+	id := name + "." + locale
+	if compiled.subject, err = template.New(id + ".subject").Parse(tmpl.Subject); err != nil {
+		return err
+	}
+	if compiled.text, err = template.New(id + ".text").Parse(tmpl.Text); err != nil {
+		return err
+	}
+	if compiled.html, err = htmltemplate.New(id + ".html").Parse(tmpl.HTML); err != nil {
+		return err
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.templates[name] == nil {
+		c.templates[name] = make(map[string]*compiledTemplate)
+	}
+	c.templates[name][normalizeLocale(locale)] = compiled
+	return nil
+}
+
+// FallbackChain returns the locales tried for locale in order,
+// e.g. fr-CA gives fr-ca, fr and the default locale
+func (c *TemplateCatalog) FallbackChain(locale string) []string {
+	chain := []string{}
+	locale = normalizeLocale(locale)
+	for locale != "" {
+		chain = append(chain, locale)
+		dash := strings.LastIndex(locale, "-")
+		if dash == -1 {
+			break
+		}
+		locale = locale[:dash]
+	}
+	if c.defaultLocale != "" && (len(chain) == 0 || chain[len(chain)-1] != c.defaultLocale) {
+		chain = append(chain, c.defaultLocale)
+	}
+	return chain
+}
+
+// Resolve returns the locale whose translation of the named template is
+// used for locale
+func (c *TemplateCatalog) Resolve(name string, locale string) (string, error) {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	translations := c.templates[name]
+	for _, candidate := range c.FallbackChain(locale) {
+		if _, ok := translations[candidate]; ok {
+			return candidate, nil
+		}
+	}
+	return "", fmt.Errorf("mail: no translation of template %q for locale %q", name, locale)
+}
+
+// Render renders the named template in locale, falling back through the
+// locale chain when there is no exact translation
+func (c *TemplateCatalog) Render(name string, locale string, data interface{}) (*Message, error) {
+	resolved, err := c.Resolve(name, locale)
+	if err != nil {
+		return nil, err
+	}
+	c.mu.RLock()
+	compiled := c.templates[name][resolved]
+	c.mu.RUnlock()
+
+	msg := InitializeMessage()
+	subject := new(bytes.Buffer)
+	if err := compiled.subject.Execute(subject, data); err != nil {
+		return nil, err
+	}
+	text := new(bytes.Buffer)
+	if err := compiled.text.Execute(text, data); err != nil {
+		return nil, err
+	}
+	html := new(bytes.Buffer)
+	if err := compiled.html.Execute(html, data); err != nil {
+		return nil, err
+	}
+	msg.Subject = strings.TrimSpace(subject.String())
+	msg.TxtMsg = text.String()
+	msg.HTMLMsg = html.String()
+	msg.Headers["Content-Language"] = []string{resolved}
+	return msg, nil
+}
+
+func normalizeLocale(locale string) string {
+	return strings.ToLower(strings.ReplaceAll(strings.TrimSpace(locale), "_", "-"))
+}
+
+// LocalizedRecipient is a recipient and the locale they read mail in,
+// an empty locale uses the default locale of the catalog
+type LocalizedRecipient struct {
+	Email  string
+	Locale string
+}
+
+// LocalizedMessage is a mail composed from a catalog template for
+// recipients who may read mail in different locales
+type LocalizedMessage struct {
+	Template    string
+	Data        interface{}
+	Recipients  []LocalizedRecipient
+	Attachments []string
+	Headers     map[string][]string
+}
+
+// LocalizedMailer renders LocalizedMessages from a catalog and sends
+// them through an IMailer
+type LocalizedMailer struct {
+	mailer  IMailer
+	catalog *TemplateCatalog
+}
+
+// NewLocalizedMailer constructs a LocalizedMailer
+func NewLocalizedMailer(mailer IMailer, catalog *TemplateCatalog) *LocalizedMailer {
+	return &LocalizedMailer{mailer: mailer, catalog: catalog}
+}
+
+// Send composes and sends the messages, a message whose recipients
+// resolve to different locales is sent as one message per locale
+func (lm *LocalizedMailer) Send(msgs ...LocalizedMessage) error {
+	composed, err := lm.Compose(msgs...)
+	if err != nil {
+		return err
+	}
+	return lm.mailer.Send(composed...)
+}
+
+// Compose renders the messages without sending them
+func (lm *LocalizedMailer) Compose(msgs ...LocalizedMessage) ([]SendableMessage, error) {
+	composed := []SendableMessage{}
+	for _, msg := range msgs {
+		locales := []string{}
+		recipients := map[string][]string{}
+		for _, rcpt := range msg.Recipients {
+			locale, err := lm.catalog.Resolve(msg.Template, rcpt.Locale)
+			if err != nil {
+				return nil, err
+			}
+			if _, ok := recipients[locale]; !ok {
+				locales = append(locales, locale)
+			}
+			recipients[locale] = append(recipients[locale], rcpt.Email)
+		}
+		for _, locale := range locales {
+			rendered, err := lm.catalog.Render(msg.Template, locale, msg.Data)
+			if err != nil {
+				return nil, err
+			}
+			rendered.Recipients = recipients[locale]
+			rendered.Attachments = append(rendered.Attachments, msg.Attachments...)
+			for key, value := range msg.Headers {
+				rendered.Headers[key] = value
+			}
+			composed = append(composed, rendered)
+		}
+	}
+	return composed, nil
+}
@@ -0,0 +1,70 @@
+package mail
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/suite"
+)
+
+type localizeTestSuite struct {
+	suite.Suite
+	catalog *TemplateCatalog
+	inner   *CapturingMailer
+	mailer  *LocalizedMailer
+}
+
+func (s *localizeTestSuite) SetupTest() {
+	s.catalog = NewTemplateCatalog("en")
+	s.Require().NoError(s.catalog.Register("welcome", "en", Template{
+		Subject: "Welcome {{.Name}}", Text: "Hello {{.Name}}", HTML: "<p>Hello {{.Name}}</p>"}))
+	s.Require().NoError(s.catalog.Register("welcome", "fr", Template{
+		Subject: "Bienvenue {{.Name}}", Text: "Bonjour {{.Name}}", HTML: "<p>Bonjour {{.Name}}</p>"}))
+	s.inner = NewCapturingMailer()
+	s.mailer = NewLocalizedMailer(s.inner, s.catalog)
+}
+
+func (s *localizeTestSuite) TestFallbackChain() {
+	s.Equal([]string{"fr-ca", "fr", "en"}, s.catalog.FallbackChain("fr_CA"))
+	s.Equal([]string{"en-gb", "en"}, s.catalog.FallbackChain("en-GB"))
+	s.Equal([]string{"en"}, s.catalog.FallbackChain(""))
+}
+
+func (s *localizeTestSuite) TestResolveFallsBack() {
+	locale, err := s.catalog.Resolve("welcome", "fr-CA")
+	s.NoError(err)
+	s.Equal("fr", locale)
+
+	locale, err = s.catalog.Resolve("welcome", "de")
+	s.NoError(err)
+	s.Equal("en", locale)
+
+	_, err = s.catalog.Resolve("missing", "en")
+	s.Error(err)
+}
+
+func (s *localizeTestSuite) TestSendSplitsRecipientsByLocale() {
+	err := s.mailer.Send(LocalizedMessage{
+		Template: "welcome",
+		Data:     map[string]string{"Name": "Ada & co"},
+		Recipients: []LocalizedRecipient{
+			{Email: "a@a.com", Locale: "fr-CA"},
+			{Email: "b@b.com", Locale: "en-US"},
+			{Email: "c@c.com", Locale: "fr"},
+			{Email: "d@d.com"},
+		},
+	})
+
+	s.Require().NoError(err)
+	messages := s.inner.Messages()
+	s.Require().Len(messages, 2)
+	s.Equal("Bienvenue Ada & co", messages[0].Subject)
+	s.Equal([]string{"a@a.com", "c@c.com"}, messages[0].To)
+	s.Equal("<p>Bonjour Ada &amp; co</p>", messages[0].HTML)
+	s.Equal([]string{"fr"}, messages[0].Headers["Content-Language"])
+	s.Equal("Welcome Ada & co", messages[1].Subject)
+	s.Equal([]string{"b@b.com", "d@d.com"}, messages[1].To)
+}
+
+func TestLocalizedMailer(t *testing.T) {
+	suite.Run(t, new(localizeTestSuite))
+}
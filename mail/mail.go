@@ -4,6 +4,7 @@ import (
 	"fmt"
 	"strings"
 
+	"github.com/dino16m/golearn-core/config"
 	"gopkg.in/gomail.v2"
 )
 
@@ -11,40 +12,58 @@ import (
 type Mailer struct {
 	senderName  string
 	senderEmail string
-	dialer      IDialer
+	transport   Transport
+	beforeSend  []BeforeSendHook
+	afterSend   []AfterSendHook
 }
 
 // NewMailer construct the mailer object
 func NewMailer(
 	sendername string, senderemail string, host string, port int,
 	username string, password string) *Mailer {
-	dialer := gomail.NewDialer(host, port, username, password)
+	transport := NewSMTPTransport(host, port, username, password)
+	return NewTransportMailer(sendername, senderemail, transport)
+}
+
+// NewTransportMailer constructs a mailer which delivers through the given transport
+func NewTransportMailer(sendername string, senderemail string, transport Transport) *Mailer {
 	return &Mailer{
-		senderName: sendername, dialer: dialer,
+		senderName: sendername, transport: transport,
 		senderEmail: senderemail}
 }
 
-// Send sends all the SendableMessages using a single connection
-func (mailer *Mailer) Send(msgs ...SendableMessage) error {
-	messages := mailer.buildMessages(msgs...)
-	if err := mailer.dialer.DialAndSend(messages...); err != nil {
-		return err
+// NewMailerFromConfig constructs a mailer using the transport selected by cfg
+func NewMailerFromConfig(cfg config.MailConfig) (*Mailer, error) {
+	transport, err := NewTransport(cfg)
+	if err != nil {
+		return nil, err
 	}
-	return nil
+	return NewTransportMailer(cfg.SenderName, cfg.SenderEmail, transport), nil
+}
+
+// Send sends all the SendableMessages using the mailer's transport,
+// it stops at the first message which fails.
+// The before send hooks may modify or veto each message first
+func (mailer *Mailer) Send(msgs ...SendableMessage) error {
+	return mailer.SendBatch(StopOnError, msgs...).Err()
+}
+
+func (mailer *Mailer) sender() Sender {
+	return Sender{Name: mailer.senderName, Email: mailer.senderEmail}
 }
 
-func (mailer *Mailer) buildMessages(msgs ...SendableMessage) []*gomail.Message {
+func buildMessages(sender Sender, msgs ...SendableMessage) []*gomail.Message {
 	messages := []*gomail.Message{}
 	for _, msg := range msgs {
-		message := mailer.buildMessage(msg)
+		message := buildMessage(sender, msg)
 		messages = append(messages, message)
 	}
 	return messages
 }
 
-func (mailer *Mailer) buildMessage(msg SendableMessage) *gomail.Message {
+func buildMessage(sender Sender, msg SendableMessage) *gomail.Message {
 	m := gomail.NewMessage()
-	m.SetAddressHeader("From", mailer.senderEmail, mailer.senderName)
+	m.SetAddressHeader("From", sender.Email, sender.Name)
 	to := msg.GetRecipients()
 	setRecipients(m, "To", to...)
 	cc := msg.GetCc()
@@ -166,18 +185,18 @@ func (m *Message) GetRecipients() []string {
 // ConsoleMailer is an implementation of the IMailer interface which writes mails
 // to the console, it is suitable for debugging mails and for use in dev environments
 type ConsoleMailer struct {
-	mailer *Mailer
+	sender Sender
 }
 
 // NewConsoleMailer constructs an innstance of ConsoleMailer
 func NewConsoleMailer() *ConsoleMailer {
-	return &ConsoleMailer{&Mailer{}}
+	return &ConsoleMailer{}
 }
 
 // Send builds emails from the provided messages and prints
 // each email to the console
 func (cm *ConsoleMailer) Send(msgs ...SendableMessage) error {
-	messages := cm.mailer.buildMessages(msgs...)
+	messages := buildMessages(cm.sender, msgs...)
 	for _, msg := range messages {
 		strBuilder := new(strings.Builder)
 		msg.WriteTo(strBuilder)
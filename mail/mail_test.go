@@ -74,7 +74,7 @@ func (s *mailerTestSuite) SetupTest() {
 	s.senderName = "dummy"
 	s.senderEmail = "root@dummy.com"
 	s.mailer = &Mailer{
-		dialer:      dialer,
+		transport:   &SMTPTransport{dialer: dialer},
 		senderName:  s.senderName,
 		senderEmail: s.senderEmail,
 	}
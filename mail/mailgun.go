@@ -0,0 +1,107 @@
+package mail
+
+import (
+	"bytes"
+	"mime/multipart"
+	"net/http"
+)
+
+const mailgunEndpoint = "https://api.mailgun.net"
+
+// MailgunTransport delivers messages through the Mailgun messages API
+type MailgunTransport struct {
+	domain   string
+	apiKey   string
+	endpoint string
+	client   *http.Client
+}
+
+// NewMailgunTransport constructs a MailgunTransport for the given sending
+// domain, an empty endpoint defaults to the US region of the Mailgun API
+func NewMailgunTransport(domain string, apiKey string, endpoint string) *MailgunTransport {
+	if endpoint == "" {
+		endpoint = mailgunEndpoint
+	}
+	return &MailgunTransport{
+		domain: domain, apiKey: apiKey,
+		endpoint: endpoint, client: apiClient}
+}
+
+// Deliver sends each message with a separate API call, it stops at the
+// first message which fails
+func (t *MailgunTransport) Deliver(sender Sender, msgs ...SendableMessage) error {
+	return firstError(t.DeliverEach(sender, StopOnError, msgs...))
+}
+
+// DeliverEach sends each message with a separate API call and reports the
+// outcome of each of them
+func (t *MailgunTransport) DeliverEach(sender Sender, mode SendMode, msgs ...SendableMessage) []error {
+	return sendEach(len(msgs), mode, func(i int) error {
+		return t.send(sender, msgs[i])
+	})
+}
+
+func (t *MailgunTransport) send(sender Sender, msg SendableMessage) error {
+	body, contentType, err := t.buildForm(sender, msg)
+	if err != nil {
+		return err
+	}
+	url := t.endpoint + "/v3/" + t.domain + "/messages"
+	req, err := http.NewRequest(http.MethodPost, url, body)
+	if err != nil {
+		return err
+	}
+	req.SetBasicAuth("api", t.apiKey)
+	req.Header.Set("Content-Type", contentType)
+	return doRequest(TransportMailgun, t.client, req)
+}
+
+func (t *MailgunTransport) buildForm(sender Sender, msg SendableMessage) (*bytes.Buffer, string, error) {
+	attachments, err := readAttachments(msg)
+	if err != nil {
+		return nil, "", err
+	}
+	body := new(bytes.Buffer)
+	form := multipart.NewWriter(body)
+
+	fields := [][2]string{
+		{"from", formatSender(sender)},
+		{"subject", subjectOf(msg)},
+	}
+	for _, rcpt := range msg.GetRecipients() {
+		fields = append(fields, [2]string{"to", rcpt})
+	}
+	for _, rcpt := range msg.GetCc() {
+		fields = append(fields, [2]string{"cc", rcpt})
+	}
+	for _, rcpt := range msg.GetBCc() {
+		fields = append(fields, [2]string{"bcc", rcpt})
+	}
+	if text := msg.GetTextMessage(); text != "" {
+		fields = append(fields, [2]string{"text", text})
+	}
+	if html := msg.GetHTMLMessage(); html != "" {
+		fields = append(fields, [2]string{"html", html})
+	}
+	for key, value := range customHeaders(msg) {
+		fields = append(fields, [2]string{"h:" + key, value})
+	}
+	for _, field := range fields {
+		if err := form.WriteField(field[0], field[1]); err != nil {
+			return nil, "", err
+		}
+	}
+	for _, file := range attachments {
+		part, err := form.CreateFormFile("attachment", file.filename)
+		if err != nil {
+			return nil, "", err
+		}
+		if _, err := part.Write(file.content); err != nil {
+			return nil, "", err
+		}
+	}
+	if err := form.Close(); err != nil {
+		return nil, "", err
+	}
+	return body, form.FormDataContentType(), nil
+}
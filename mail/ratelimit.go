@@ -0,0 +1,272 @@
+package mail
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"strings"
+	"sync"
+	"time"
+)
+
+// RateLimitMode decides what a RateLimitedMailer does with a message
+// which exceeds one of its limits
+type RateLimitMode int
+
+const (
+	// QueueOnLimit holds the message back until it can be sent
+	QueueOnLimit RateLimitMode = iota
+	// RejectOnLimit fails the message with a RateLimitError
+	RejectOnLimit
+)
+
+// AnyDomain is the DomainPerSecond key applied to recipient domains
+// which have no limit of their own
+const AnyDomain = "*"
+
+// ErrRateLimited is matched by every RateLimitError
+var ErrRateLimited = errors.New("mail: rate limit exceeded")
+
+// RateLimitError is returned for a message rejected by a RateLimitedMailer
+type RateLimitError struct {
+	// Limit names the limit which was exceeded, e.g. "global",
+	// "domain example.com" or "recipient me@example.com"
+	Limit      string
+	RetryAfter time.Duration
+}
+
+func (e RateLimitError) Error() string {
+	return fmt.Sprintf("mail: rate limit exceeded for %s, retry after %s", e.Limit, e.RetryAfter)
+}
+
+// Is makes errors.Is(err, ErrRateLimited) true for every RateLimitError
+func (e RateLimitError) Is(target error) bool {
+	return target == ErrRateLimited
+}
+
+// RateLimits configures a RateLimitedMailer, a zero value disables a limit
+type RateLimits struct {
+	// PerSecond is the number of messages sent per second across all recipients
+	PerSecond float64
+	// DomainPerSecond is the number of recipients per second of each
+	// recipient domain, the AnyDomain key applies to unlisted domains
+	DomainPerSecond map[string]float64
+	// RecipientCooldown is the minimum time between two messages to the
+	// same recipient
+	RecipientCooldown time.Duration
+	Mode              RateLimitMode
+	// MaxWait is the longest QueueOnLimit holds a message back, messages
+	// which would wait longer are rejected. Zero waits indefinitely
+	MaxWait time.Duration
+}
+
+// bucket is a token bucket holding at most one second worth of tokens,
+// tokens go negative when sends are queued ahead of time
+type bucket struct {
+	rate   float64
+	tokens float64
+	last   time.Time
+}
+
+func newBucket(rate float64, now time.Time) *bucket {
+	return &bucket{rate: rate, tokens: math.Max(rate, 1), last: now}
+}
+
+func (b *bucket) refill(now time.Time) {
+	elapsed := now.Sub(b.last).Seconds()
+	b.tokens = math.Min(math.Max(b.rate, 1), b.tokens+elapsed*b.rate)
+	b.last = now
+}
+
+// wait returns how long until n tokens are available
+func (b *bucket) wait(now time.Time, n float64) time.Duration {
+	b.refill(now)
+	if b.tokens >= n {
+		return 0
+	}
+	return time.Duration((n - b.tokens) / b.rate * float64(time.Second))
+}
+
+func (b *bucket) take(n float64) {
+	b.tokens -= n
+}
+
+// RateLimitedMailer is an IMailer decorator which limits how fast messages
+// are handed to the wrapped mailer. Messages are sent one at a time so
+// every message is throttled on its own
+type RateLimitedMailer struct {
+	mailer   IMailer
+	limits   RateLimits
+	global   *bucket
+	domains  map[string]*bucket
+	lastSent map[string]time.Time
+	swept    time.Time
+	now      func() time.Time
+	sleep    func(time.Duration)
+	mu       sync.Mutex
+}
+
+// NewRateLimitedMailer wraps mailer with the given limits, the domains of
+// DomainPerSecond are matched case insensitively
+func NewRateLimitedMailer(mailer IMailer, limits RateLimits) *RateLimitedMailer {
+	if limits.DomainPerSecond != nil {
+		domains := make(map[string]float64, len(limits.DomainPerSecond))
+		for domain, rate := range limits.DomainPerSecond {
+			domains[strings.ToLower(domain)] = rate
+		}
+		limits.DomainPerSecond = domains
+	}
+	return &RateLimitedMailer{
+		mailer:   mailer,
+		limits:   limits,
+		domains:  make(map[string]*bucket),
+		lastSent: make(map[string]time.Time),
+		now:      time.Now,
+		sleep:    time.Sleep,
+	}
+}
+
+// Send sends the messages one at a time, holding each back or rejecting
+// it according to the limits. It stops at the first message which fails
+func (m *RateLimitedMailer) Send(msgs ...SendableMessage) error {
+	for i, msg := range msgs {
+		wait, err := m.reserve(msg)
+		if err != nil {
+			return fmt.Errorf("mail: could not send email %d: %w", i+1, err)
+		}
+		if wait > 0 {
+			m.sleep(wait)
+		}
+		if err := m.mailer.Send(msg); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// reserve claims the capacity needed by msg and returns how long to
+// wait before sending it
+func (m *RateLimitedMailer) reserve(msg SendableMessage) (time.Duration, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	now := m.now()
+	recipients := recipientsOf(msg)
+
+	var wait time.Duration
+	var limit string
+	consider := func(name string, d time.Duration) {
+		if d > wait {
+			wait, limit = d, name
+		}
+	}
+
+	global := m.globalBucket(now)
+	if global != nil {
+		consider("global", global.wait(now, 1))
+	}
+
+	perDomain := map[string]float64{}
+	for _, rcpt := range recipients {
+		perDomain[domainOf(rcpt)]++
+	}
+	domainBuckets := map[string]*bucket{}
+	for domain, count := range perDomain {
+		if b := m.domainBucket(domain, now); b != nil {
+			domainBuckets[domain] = b
+			consider("domain "+domain, b.wait(now, count))
+		}
+	}
+
+	if cooldown := m.limits.RecipientCooldown; cooldown > 0 {
+		m.forgetCooledDown(now)
+		for _, rcpt := range recipients {
+			if last, ok := m.lastSent[rcpt]; ok {
+				consider("recipient "+rcpt, last.Add(cooldown).Sub(now))
+			}
+		}
+	}
+
+	if wait > 0 && (m.limits.Mode == RejectOnLimit ||
+		(m.limits.MaxWait > 0 && wait > m.limits.MaxWait)) {
+		return 0, RateLimitError{Limit: limit, RetryAfter: wait}
+	}
+
+	if global != nil {
+		global.take(1)
+	}
+	for domain, b := range domainBuckets {
+		b.take(perDomain[domain])
+	}
+	if m.limits.RecipientCooldown > 0 {
+		for _, rcpt := range recipients {
+			m.lastSent[rcpt] = now.Add(wait)
+		}
+	}
+	return wait, nil
+}
+
+func (m *RateLimitedMailer) globalBucket(now time.Time) *bucket {
+	if m.limits.PerSecond <= 0 {
+		return nil
+	}
+	if m.global == nil {
+		m.global = newBucket(m.limits.PerSecond, now)
+	}
+	return m.global
+}
+
+func (m *RateLimitedMailer) domainBucket(domain string, now time.Time) *bucket {
+	rate, ok := m.limits.DomainPerSecond[domain]
+	if !ok {
+		rate = m.limits.DomainPerSecond[AnyDomain]
+	}
+	if rate <= 0 {
+		return nil
+	}
+	b, ok := m.domains[domain]
+	if !ok {
+		b = newBucket(rate, now)
+		m.domains[domain] = b
+	}
+	return b
+}
+
+// forgetCooledDown drops recipients whose cooldown has elapsed so the
+// cooldown map does not grow forever, it sweeps at most once per cooldown
+func (m *RateLimitedMailer) forgetCooledDown(now time.Time) {
+	if now.Sub(m.swept) < m.limits.RecipientCooldown {
+		return
+	}
+	m.swept = now
+	for rcpt, last := range m.lastSent {
+		if !now.Before(last.Add(m.limits.RecipientCooldown)) {
+			delete(m.lastSent, rcpt)
+		}
+	}
+}
+
+func recipientsOf(msg SendableMessage) []string {
+	seen := map[string]bool{}
+	recipients := []string{}
+	for _, list := range [][]string{msg.GetRecipients(), msg.GetCc(), msg.GetBCc()} {
+		for _, rcpt := range list {
+			if addr, err := parseAddress(rcpt); err == nil {
+				rcpt = addr
+			}
+			rcpt = strings.ToLower(rcpt)
+			if !seen[rcpt] {
+				seen[rcpt] = true
+				recipients = append(recipients, rcpt)
+			}
+		}
+	}
+	return recipients
+}
+
+func domainOf(email string) string {
+	at := strings.LastIndex(email, "@")
+	if at == -1 {
+		return ""
+	}
+	return strings.ToLower(email[at+1:])
+}
@@ -0,0 +1,117 @@
+package mail
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/suite"
+)
+
+type rateLimitTestSuite struct {
+	suite.Suite
+	inner *CapturingMailer
+	now   time.Time
+	slept time.Duration
+}
+
+func (s *rateLimitTestSuite) SetupTest() {
+	s.inner = NewCapturingMailer()
+	s.now = time.Date(2023, 5, 1, 12, 0, 0, 0, time.UTC)
+	s.slept = 0
+}
+
+func (s *rateLimitTestSuite) mailer(limits RateLimits) *RateLimitedMailer {
+	mailer := NewRateLimitedMailer(s.inner, limits)
+	mailer.now = func() time.Time { return s.now }
+	mailer.sleep = func(d time.Duration) {
+		s.slept += d
+		s.now = s.now.Add(d)
+	}
+	return mailer
+}
+
+func (s *rateLimitTestSuite) message(recipients ...string) *dummyMessage {
+	msg := initializeMsg()
+	msg.txtMsg = "Hello there"
+	msg.recipients = append(msg.recipients, recipients...)
+	return msg
+}
+
+func (s *rateLimitTestSuite) TestGlobalLimitQueuesMessages() {
+	mailer := s.mailer(RateLimits{PerSecond: 2})
+
+	err := mailer.Send(
+		s.message("a@a.com"), s.message("b@b.com"), s.message("c@c.com"), s.message("d@d.com"))
+
+	s.NoError(err)
+	s.Len(s.inner.Messages(), 4)
+	s.Equal(time.Second, s.slept)
+}
+
+func (s *rateLimitTestSuite) TestGlobalLimitRejectsMessages() {
+	mailer := s.mailer(RateLimits{PerSecond: 1, Mode: RejectOnLimit})
+
+	err := mailer.Send(s.message("a@a.com"), s.message("b@b.com"))
+
+	s.True(errors.Is(err, ErrRateLimited))
+	var limitErr RateLimitError
+	s.Require().ErrorAs(err, &limitErr)
+	s.Equal("global", limitErr.Limit)
+	s.Equal(time.Second, limitErr.RetryAfter)
+	s.Len(s.inner.Messages(), 1)
+}
+
+func (s *rateLimitTestSuite) TestDomainLimitOnlyThrottlesThatDomain() {
+	mailer := s.mailer(RateLimits{
+		DomainPerSecond: map[string]float64{"slow.com": 1},
+		Mode:            RejectOnLimit,
+	})
+
+	s.NoError(mailer.Send(s.message("a@slow.com"), s.message("a@fast.com"), s.message("b@fast.com")))
+	err := mailer.Send(s.message("b@SLOW.com"))
+
+	var limitErr RateLimitError
+	s.Require().ErrorAs(err, &limitErr)
+	s.Equal("domain slow.com", limitErr.Limit)
+}
+
+func (s *rateLimitTestSuite) TestDomainLimitIgnoresCase() {
+	mailer := s.mailer(RateLimits{
+		DomainPerSecond: map[string]float64{"Slow.com": 1},
+		Mode:            RejectOnLimit,
+	})
+
+	s.NoError(mailer.Send(s.message("User@Slow.COM")))
+	err := mailer.Send(s.message("user@slow.com"))
+
+	var limitErr RateLimitError
+	s.Require().ErrorAs(err, &limitErr)
+	s.Equal("domain slow.com", limitErr.Limit)
+}
+
+func (s *rateLimitTestSuite) TestRecipientCooldown() {
+	mailer := s.mailer(RateLimits{RecipientCooldown: time.Minute, Mode: RejectOnLimit})
+
+	s.NoError(mailer.Send(s.message("a@a.com")))
+	s.Error(mailer.Send(s.message("a@a.com")))
+	s.NoError(mailer.Send(s.message("b@a.com")))
+
+	s.now = s.now.Add(time.Minute)
+	s.NoError(mailer.Send(s.message("a@a.com")))
+	s.Len(s.inner.Messages(), 3)
+}
+
+func (s *rateLimitTestSuite) TestQueueRejectsBeyondMaxWait() {
+	mailer := s.mailer(RateLimits{RecipientCooldown: time.Hour, MaxWait: time.Minute})
+
+	s.NoError(mailer.Send(s.message("a@a.com")))
+	err := mailer.Send(s.message("a@a.com"))
+
+	s.True(errors.Is(err, ErrRateLimited))
+	s.Zero(s.slept)
+}
+
+func TestRateLimitedMailer(t *testing.T) {
+	suite.Run(t, new(rateLimitTestSuite))
+}
@@ -0,0 +1,131 @@
+package mail
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/json"
+	"net/http"
+)
+
+const sendGridEndpoint = "https://api.sendgrid.com"
+
+// SendGridTransport delivers messages through the SendGrid v3 mail send API
+type SendGridTransport struct {
+	apiKey   string
+	endpoint string
+	client   *http.Client
+}
+
+// NewSendGridTransport constructs a SendGridTransport, an empty endpoint
+// defaults to the public SendGrid API
+func NewSendGridTransport(apiKey string, endpoint string) *SendGridTransport {
+	if endpoint == "" {
+		endpoint = sendGridEndpoint
+	}
+	return &SendGridTransport{apiKey: apiKey, endpoint: endpoint, client: apiClient}
+}
+
+type sendGridAddress struct {
+	Email string `json:"email"`
+	Name  string `json:"name,omitempty"`
+}
+
+type sendGridPersonalization struct {
+	To  []sendGridAddress `json:"to"`
+	Cc  []sendGridAddress `json:"cc,omitempty"`
+	Bcc []sendGridAddress `json:"bcc,omitempty"`
+}
+
+type sendGridContent struct {
+	Type  string `json:"type"`
+	Value string `json:"value"`
+}
+
+type sendGridAttachment struct {
+	Content     string `json:"content"`
+	Filename    string `json:"filename"`
+	Type        string `json:"type"`
+	Disposition string `json:"disposition"`
+}
+
+type sendGridPayload struct {
+	Personalizations []sendGridPersonalization `json:"personalizations"`
+	From             sendGridAddress           `json:"from"`
+	Subject          string                    `json:"subject"`
+	Content          []sendGridContent         `json:"content"`
+	Attachments      []sendGridAttachment      `json:"attachments,omitempty"`
+	Headers          map[string]string         `json:"headers,omitempty"`
+}
+
+// Deliver sends each message with a separate API call, it stops at the
+// first message which fails
+func (t *SendGridTransport) Deliver(sender Sender, msgs ...SendableMessage) error {
+	return firstError(t.DeliverEach(sender, StopOnError, msgs...))
+}
+
+// DeliverEach sends each message with a separate API call and reports the
+// outcome of each of them
+func (t *SendGridTransport) DeliverEach(sender Sender, mode SendMode, msgs ...SendableMessage) []error {
+	return sendEach(len(msgs), mode, func(i int) error {
+		return t.send(sender, msgs[i])
+	})
+}
+
+func (t *SendGridTransport) send(sender Sender, msg SendableMessage) error {
+	payload, err := t.buildPayload(sender, msg)
+	if err != nil {
+		return err
+	}
+	body, err := json.Marshal(payload)
+	if err != nil {
+		return err
+	}
+	req, err := http.NewRequest(http.MethodPost, t.endpoint+"/v3/mail/send", bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Authorization", "Bearer "+t.apiKey)
+	req.Header.Set("Content-Type", "application/json")
+	return doRequest(TransportSendGrid, t.client, req)
+}
+
+func (t *SendGridTransport) buildPayload(sender Sender, msg SendableMessage) (sendGridPayload, error) {
+	attachments, err := readAttachments(msg)
+	if err != nil {
+		return sendGridPayload{}, err
+	}
+	payload := sendGridPayload{
+		Personalizations: []sendGridPersonalization{{
+			To:  sendGridAddresses(msg.GetRecipients()),
+			Cc:  sendGridAddresses(msg.GetCc()),
+			Bcc: sendGridAddresses(msg.GetBCc()),
+		}},
+		From:    sendGridAddress{Email: sender.Email, Name: sender.Name},
+		Subject: subjectOf(msg),
+		Headers: customHeaders(msg),
+	}
+	// SendGrid requires text/plain to come before text/html
+	if text := msg.GetTextMessage(); text != "" {
+		payload.Content = append(payload.Content, sendGridContent{Type: "text/plain", Value: text})
+	}
+	if html := msg.GetHTMLMessage(); html != "" {
+		payload.Content = append(payload.Content, sendGridContent{Type: "text/html", Value: html})
+	}
+	for _, file := range attachments {
+		payload.Attachments = append(payload.Attachments, sendGridAttachment{
+			Content:     base64.StdEncoding.EncodeToString(file.content),
+			Filename:    file.filename,
+			Type:        file.contentType,
+			Disposition: "attachment",
+		})
+	}
+	return payload, nil
+}
+
+func sendGridAddresses(emails []string) []sendGridAddress {
+	addresses := []sendGridAddress{}
+	for _, email := range emails {
+		addresses = append(addresses, sendGridAddress{Email: email})
+	}
+	return addresses
+}
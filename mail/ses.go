@@ -0,0 +1,150 @@
+package mail
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"time"
+)
+
+// SESTransport delivers messages through the Amazon SES v2 API, messages are
+// rendered to MIME and sent as raw content so attachments and custom headers
+// are preserved
+type SESTransport struct {
+	region          string
+	accessKeyID     string
+	secretAccessKey string
+	endpoint        string
+	client          *http.Client
+	now             func() time.Time
+}
+
+// NewSESTransport constructs an SESTransport, an empty endpoint defaults to
+// the SES endpoint of the region
+func NewSESTransport(region string, accessKeyID string, secretAccessKey string, endpoint string) *SESTransport {
+	if endpoint == "" {
+		endpoint = fmt.Sprintf("https://email.%s.amazonaws.com", region)
+	}
+	return &SESTransport{
+		region: region, accessKeyID: accessKeyID,
+		secretAccessKey: secretAccessKey, endpoint: endpoint,
+		client: apiClient, now: time.Now}
+}
+
+type sesDestination struct {
+	ToAddresses  []string `json:"ToAddresses,omitempty"`
+	CcAddresses  []string `json:"CcAddresses,omitempty"`
+	BccAddresses []string `json:"BccAddresses,omitempty"`
+}
+
+type sesRawContent struct {
+	Data []byte `json:"Data"`
+}
+
+type sesContent struct {
+	Raw sesRawContent `json:"Raw"`
+}
+
+type sesPayload struct {
+	FromEmailAddress string         `json:"FromEmailAddress"`
+	Destination      sesDestination `json:"Destination"`
+	Content          sesContent     `json:"Content"`
+}
+
+// withoutBcc hides the blind copies of a message so they are not rendered
+// into the raw MIME headers, SES receives them in the destination instead
+type withoutBcc struct {
+	SendableMessage
+}
+
+func (m withoutBcc) GetBCc() []string {
+	return []string{}
+}
+
+// Deliver sends each message with a separate API call, it stops at the
+// first message which fails
+func (t *SESTransport) Deliver(sender Sender, msgs ...SendableMessage) error {
+	return firstError(t.DeliverEach(sender, StopOnError, msgs...))
+}
+
+// DeliverEach sends each message with a separate API call and reports the
+// outcome of each of them
+func (t *SESTransport) DeliverEach(sender Sender, mode SendMode, msgs ...SendableMessage) []error {
+	return sendEach(len(msgs), mode, func(i int) error {
+		return t.send(sender, msgs[i])
+	})
+}
+
+func (t *SESTransport) send(sender Sender, msg SendableMessage) error {
+	raw := new(bytes.Buffer)
+	if _, err := buildMessage(sender, withoutBcc{msg}).WriteTo(raw); err != nil {
+		return err
+	}
+	payload := sesPayload{
+		FromEmailAddress: formatSender(sender),
+		Destination: sesDestination{
+			ToAddresses:  msg.GetRecipients(),
+			CcAddresses:  msg.GetCc(),
+			BccAddresses: msg.GetBCc(),
+		},
+		Content: sesContent{Raw: sesRawContent{Data: raw.Bytes()}},
+	}
+	body, err := json.Marshal(payload)
+	if err != nil {
+		return err
+	}
+	req, err := http.NewRequest(http.MethodPost, t.endpoint+"/v2/email/outbound-emails", bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	t.sign(req, body)
+	return doRequest(TransportSES, t.client, req)
+}
+
+// sign adds an AWS signature version 4 Authorization header to req
+func (t *SESTransport) sign(req *http.Request, body []byte) {
+	now := t.now().UTC()
+	amzDate := now.Format("20060102T150405Z")
+	date := now.Format("20060102")
+	req.Header.Set("X-Amz-Date", amzDate)
+
+	path := req.URL.EscapedPath()
+	if path == "" {
+		path = "/"
+	}
+	signedHeaders := "content-type;host;x-amz-date"
+	canonicalHeaders := fmt.Sprintf("content-type:%s\nhost:%s\nx-amz-date:%s\n",
+		req.Header.Get("Content-Type"), req.URL.Host, amzDate)
+	canonicalRequest := fmt.Sprintf("%s\n%s\n%s\n%s\n%s\n%s",
+		req.Method, path, req.URL.RawQuery, canonicalHeaders, signedHeaders, hashHex(body))
+
+	scope := fmt.Sprintf("%s/%s/ses/aws4_request", date, t.region)
+	stringToSign := fmt.Sprintf("AWS4-HMAC-SHA256\n%s\n%s\n%s",
+		amzDate, scope, hashHex([]byte(canonicalRequest)))
+
+	key := hmacSHA256([]byte("AWS4"+t.secretAccessKey), date)
+	key = hmacSHA256(key, t.region)
+	key = hmacSHA256(key, "ses")
+	key = hmacSHA256(key, "aws4_request")
+	signature := hex.EncodeToString(hmacSHA256(key, stringToSign))
+
+	req.Header.Set("Authorization", fmt.Sprintf(
+		"AWS4-HMAC-SHA256 Credential=%s/%s, SignedHeaders=%s, Signature=%s",
+		t.accessKeyID, scope, signedHeaders, signature))
+}
+
+func hashHex(data []byte) string {
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:])
+}
+
+func hmacSHA256(key []byte, data string) []byte {
+	mac := hmac.New(sha256.New, key)
+	mac.Write([]byte(data))
+	return mac.Sum(nil)
+}
@@ -0,0 +1,236 @@
+package mail
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"mime"
+	"net/http"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/dino16m/golearn-core/config"
+	"gopkg.in/gomail.v2"
+)
+
+// Names of the transports which can be selected with config.MailConfig
+const (
+	TransportSMTP     = "smtp"
+	TransportSendGrid = "sendgrid"
+	TransportMailgun  = "mailgun"
+	TransportSES      = "ses"
+)
+
+// SMTPTransport delivers messages over SMTP, all messages passed
+// to a single Deliver call share a connection when the dialer is an
+// ISendDialer
+type SMTPTransport struct {
+	dialer IDialer
+	signer *DKIMSigner
+}
+
+// NewSMTPTransport constructs an SMTPTransport for the given server
+func NewSMTPTransport(host string, port int, username string, password string) *SMTPTransport {
+	return &SMTPTransport{dialer: gomail.NewDialer(host, port, username, password)}
+}
+
+// SetDKIMSigner makes the transport DKIM sign every message before
+// sending it, the dialer must implement ISendDialer
+func (t *SMTPTransport) SetDKIMSigner(signer *DKIMSigner) {
+	t.signer = signer
+}
+
+// Deliver builds the messages and sends them over a single SMTP connection,
+// it stops at the first message which fails
+func (t *SMTPTransport) Deliver(sender Sender, msgs ...SendableMessage) error {
+	return firstError(t.DeliverEach(sender, StopOnError, msgs...))
+}
+
+// DeliverEach builds the messages and sends them over a single SMTP
+// connection, reporting the outcome of each message. A message the server
+// refuses may leave its SMTP transaction open, so the connection is closed
+// and the next message is sent over a new one
+func (t *SMTPTransport) DeliverEach(sender Sender, mode SendMode, msgs ...SendableMessage) []error {
+	messages := buildMessages(sender, msgs...)
+	dialer, ok := t.dialer.(ISendDialer)
+	if !ok {
+		if t.signer != nil {
+			err := errors.New("mail: the dialer does not support sending DKIM signed messages")
+			return sendEach(len(messages), mode, func(int) error { return err })
+		}
+		// a dialer which cannot Dial opens a connection for every message,
+		// gomail.Dialer can
+		return sendEach(len(messages), mode, func(i int) error {
+			return t.dialer.DialAndSend(messages[i])
+		})
+	}
+	var sc gomail.SendCloser
+	var dialErr error
+	defer func() {
+		if sc != nil {
+			sc.Close()
+		}
+	}()
+	return sendEach(len(messages), mode, func(i int) error {
+		from, to, payload, err := t.prepare(messages[i])
+		if err != nil {
+			return err
+		}
+		if dialErr != nil {
+			return dialErr
+		}
+		if sc == nil {
+			if sc, dialErr = dialer.Dial(); dialErr != nil {
+				sc = nil
+				return dialErr
+			}
+		}
+		if err := sc.Send(from, to, payload); err != nil {
+			sc.Close()
+			sc = nil
+			return err
+		}
+		return nil
+	})
+}
+
+// prepare returns the envelope of m and what is sent for it, the message is
+// DKIM signed when a signer is set
+func (t *SMTPTransport) prepare(m *gomail.Message) (string, []string, io.WriterTo, error) {
+	from, to, err := envelope(m)
+	if err != nil {
+		return "", nil, nil, err
+	}
+	if t.signer == nil {
+		return from, to, m, nil
+	}
+	signed, err := t.signer.Sign(m)
+	if err != nil {
+		return "", nil, nil, err
+	}
+	return from, to, rawMessage(signed), nil
+}
+
+// NewTransport constructs the transport selected by cfg.Transport
+func NewTransport(cfg config.MailConfig) (Transport, error) {
+	switch strings.ToLower(cfg.Transport) {
+	case "", TransportSMTP:
+		transport := NewSMTPTransport(cfg.Host, cfg.Port, cfg.Username, cfg.Password)
+		if cfg.DKIMPrivateKey != "" {
+			signer, err := NewDKIMSigner(cfg.DKIMDomain, cfg.DKIMSelector, []byte(cfg.DKIMPrivateKey))
+			if err != nil {
+				return nil, err
+			}
+			transport.SetDKIMSigner(signer)
+		}
+		return transport, nil
+	case TransportSendGrid:
+		return NewSendGridTransport(cfg.APIKey, cfg.Endpoint), nil
+	case TransportMailgun:
+		return NewMailgunTransport(cfg.Domain, cfg.APIKey, cfg.Endpoint), nil
+	case TransportSES:
+		return NewSESTransport(cfg.Region, cfg.AccessKeyID, cfg.SecretAccessKey, cfg.Endpoint), nil
+	default:
+		return nil, fmt.Errorf("mail: unknown transport %q", cfg.Transport)
+	}
+}
+
+// ProviderError is returned by the HTTP API transports when the provider
+// responds with a non success status code
+type ProviderError struct {
+	Provider   string
+	StatusCode int
+	Body       string
+}
+
+func (e ProviderError) Error() string {
+	return fmt.Sprintf("mail: %s responded with status %d: %s", e.Provider, e.StatusCode, e.Body)
+}
+
+// apiClient is the http client shared by the HTTP API transports
+var apiClient = &http.Client{Timeout: 30 * time.Second}
+
+func doRequest(provider string, client *http.Client, req *http.Request) error {
+	res, err := client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer res.Body.Close()
+	if res.StatusCode < 200 || res.StatusCode > 299 {
+		body, _ := ioutil.ReadAll(io.LimitReader(res.Body, 4096))
+		return ProviderError{
+			Provider: provider, StatusCode: res.StatusCode,
+			Body: strings.TrimSpace(string(body))}
+	}
+	return nil
+}
+
+// firstError returns the error of the first message of errs which failed,
+// naming its position
+func firstError(errs []error) error {
+	for i, err := range errs {
+		if err != nil {
+			return fmt.Errorf("mail: could not send email %d: %w", i+1, err)
+		}
+	}
+	return nil
+}
+
+// reservedHeaders are set by the HTTP API transports from the message fields
+// and must not be passed to the providers as custom headers
+var reservedHeaders = map[string]bool{
+	"from": true, "to": true, "cc": true, "bcc": true, "subject": true,
+	"content-type": true, "content-transfer-encoding": true,
+}
+
+func customHeaders(msg SendableMessage) map[string]string {
+	headers := map[string]string{}
+	for key, value := range msg.GetHeaders() {
+		if reservedHeaders[strings.ToLower(key)] {
+			continue
+		}
+		headers[key] = strings.Join(value, ", ")
+	}
+	return headers
+}
+
+// subjectOf returns the subject of msg, falling back to a Subject header
+// as the SMTP transport lets headers override the subject
+func subjectOf(msg SendableMessage) string {
+	for key, value := range msg.GetHeaders() {
+		if strings.EqualFold(key, "Subject") && len(value) > 0 {
+			return value[0]
+		}
+	}
+	return msg.GetSubject()
+}
+
+type attachment struct {
+	filename    string
+	contentType string
+	content     []byte
+}
+
+func readAttachments(msg SendableMessage) ([]attachment, error) {
+	attachments := []attachment{}
+	for _, path := range msg.GetAttachments() {
+		content, err := ioutil.ReadFile(path)
+		if err != nil {
+			return nil, err
+		}
+		contentType := mime.TypeByExtension(filepath.Ext(path))
+		if contentType == "" {
+			contentType = "application/octet-stream"
+		}
+		attachments = append(attachments, attachment{
+			filename: filepath.Base(path), contentType: contentType,
+			content: content})
+	}
+	return attachments, nil
+}
+
+func formatSender(sender Sender) string {
+	return gomail.NewMessage().FormatAddress(sender.Email, sender.Name)
+}
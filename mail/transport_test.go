@@ -0,0 +1,209 @@
+package mail
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/json"
+	"io/ioutil"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/dino16m/golearn-core/config"
+	"github.com/stretchr/testify/suite"
+)
+
+// capturedRequest holds what the tests check of a request, it is read
+// entirely by the handler so no part of the request outlives it
+type capturedRequest struct {
+	path     string
+	header   http.Header
+	user     string
+	password string
+	hasAuth  bool
+	body     []byte
+	form     *multipart.Form
+}
+
+type transportTestSuite struct {
+	suite.Suite
+	server   *httptest.Server
+	requests []capturedRequest
+	status   int
+	sender   Sender
+	mu       sync.Mutex
+}
+
+func (s *transportTestSuite) SetupTest() {
+	s.requests = []capturedRequest{}
+	s.status = http.StatusOK
+	s.sender = Sender{Name: "dummy", Email: "root@dummy.com"}
+	s.server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := ioutil.ReadAll(r.Body)
+		captured := capturedRequest{path: r.URL.Path, header: r.Header.Clone(), body: body}
+		captured.user, captured.password, captured.hasAuth = r.BasicAuth()
+		if strings.HasPrefix(r.Header.Get("Content-Type"), "multipart/") {
+			r.Body = ioutil.NopCloser(bytes.NewReader(body))
+			if err := r.ParseMultipartForm(1 << 20); err == nil {
+				captured.form = r.MultipartForm
+			}
+		}
+		s.mu.Lock()
+		s.requests = append(s.requests, captured)
+		status := s.status
+		s.mu.Unlock()
+		w.WriteHeader(status)
+		w.Write([]byte(`{"message": "queued"}`))
+	}))
+}
+
+// captured returns the requests the server received so far
+func (s *transportTestSuite) captured() []capturedRequest {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]capturedRequest{}, s.requests...)
+}
+
+func (s *transportTestSuite) TearDownTest() {
+	s.server.Close()
+}
+
+func (s *transportTestSuite) message() *dummyMessage {
+	msg := initializeMsg()
+	msg.subject = "Welcome"
+	msg.txtMsg = "Hello there"
+	msg.htmlMsg = "<h1>Hello there</h1>"
+	msg.recipients = append(msg.recipients, "me@me.com")
+	msg.cc = append(msg.cc, "cc@me.com")
+	msg.bcc = append(msg.bcc, "bcc@me.com")
+	msg.headers["X-Campaign"] = []string{"welcome"}
+	return msg
+}
+
+func (s *transportTestSuite) attachment() string {
+	dir := s.T().TempDir()
+	path := filepath.Join(dir, "attachment.txt")
+	s.Require().NoError(os.WriteFile(path, []byte("This is an attachment"), 0666))
+	return path
+}
+
+func (s *transportTestSuite) TestSendGridPayload() {
+	transport := NewSendGridTransport("sg-key", s.server.URL)
+	msg := s.message()
+	msg.attachments = append(msg.attachments, s.attachment())
+
+	s.Require().NoError(transport.Deliver(s.sender, msg))
+
+	requests := s.captured()
+	s.Require().Len(requests, 1)
+	req := requests[0]
+	s.Equal("/v3/mail/send", req.path)
+	s.Equal("Bearer sg-key", req.header.Get("Authorization"))
+
+	var payload sendGridPayload
+	s.Require().NoError(json.Unmarshal(req.body, &payload))
+	s.Equal("Welcome", payload.Subject)
+	s.Equal(sendGridAddress{Email: s.sender.Email, Name: s.sender.Name}, payload.From)
+	s.Equal("me@me.com", payload.Personalizations[0].To[0].Email)
+	s.Equal("cc@me.com", payload.Personalizations[0].Cc[0].Email)
+	s.Equal("bcc@me.com", payload.Personalizations[0].Bcc[0].Email)
+	s.Equal("text/plain", payload.Content[0].Type)
+	s.Equal("text/html", payload.Content[1].Type)
+	s.Equal("welcome", payload.Headers["X-Campaign"])
+	s.Require().Len(payload.Attachments, 1)
+	s.Equal("attachment.txt", payload.Attachments[0].Filename)
+	content, _ := base64.StdEncoding.DecodeString(payload.Attachments[0].Content)
+	s.Equal("This is an attachment", string(content))
+}
+
+func (s *transportTestSuite) TestMailgunForm() {
+	transport := NewMailgunTransport("mg.dummy.com", "mg-key", s.server.URL)
+	msg := s.message()
+	msg.attachments = append(msg.attachments, s.attachment())
+
+	s.Require().NoError(transport.Deliver(s.sender, msg))
+
+	requests := s.captured()
+	s.Require().Len(requests, 1)
+	req := requests[0]
+	s.Equal("/v3/mg.dummy.com/messages", req.path)
+	s.True(req.hasAuth)
+	s.Equal("api", req.user)
+	s.Equal("mg-key", req.password)
+
+	form := req.form
+	s.Require().NotNil(form)
+	s.Equal([]string{`"dummy" <root@dummy.com>`}, form.Value["from"])
+	s.Equal([]string{"me@me.com"}, form.Value["to"])
+	s.Equal([]string{"bcc@me.com"}, form.Value["bcc"])
+	s.Equal([]string{"Welcome"}, form.Value["subject"])
+	s.Equal([]string{"welcome"}, form.Value["h:X-Campaign"])
+	s.Require().Len(form.File["attachment"], 1)
+	s.Equal("attachment.txt", form.File["attachment"][0].Filename)
+}
+
+func (s *transportTestSuite) TestSESRawMessage() {
+	transport := NewSESTransport("eu-west-1", "AKID", "secret", s.server.URL)
+	transport.now = func() time.Time {
+		return time.Date(2023, 5, 1, 12, 0, 0, 0, time.UTC)
+	}
+
+	s.Require().NoError(transport.Deliver(s.sender, s.message()))
+
+	requests := s.captured()
+	s.Require().Len(requests, 1)
+	req := requests[0]
+	s.Equal("/v2/email/outbound-emails", req.path)
+	s.Equal("20230501T120000Z", req.header.Get("X-Amz-Date"))
+	s.True(strings.HasPrefix(req.header.Get("Authorization"),
+		"AWS4-HMAC-SHA256 Credential=AKID/20230501/eu-west-1/ses/aws4_request"))
+
+	var payload sesPayload
+	s.Require().NoError(json.Unmarshal(req.body, &payload))
+	s.Equal([]string{"bcc@me.com"}, payload.Destination.BccAddresses)
+	raw := string(payload.Content.Raw.Data)
+	s.Contains(raw, "Subject: Welcome")
+	s.Contains(raw, "X-Campaign: welcome")
+	s.NotContains(raw, "bcc@me.com")
+}
+
+func (s *transportTestSuite) TestProviderErrorOnFailureStatus() {
+	s.mu.Lock()
+	s.status = http.StatusBadRequest
+	s.mu.Unlock()
+	transport := NewSendGridTransport("sg-key", s.server.URL)
+
+	err := transport.Deliver(s.sender, s.message())
+
+	var providerErr ProviderError
+	s.Require().ErrorAs(err, &providerErr)
+	s.Equal(TransportSendGrid, providerErr.Provider)
+	s.Equal(http.StatusBadRequest, providerErr.StatusCode)
+}
+
+func (s *transportTestSuite) TestMailerFromConfigUsesSelectedTransport() {
+	mailer, err := NewMailerFromConfig(config.MailConfig{
+		Transport: TransportSendGrid, APIKey: "sg-key", Endpoint: s.server.URL,
+		SenderName: "dummy", SenderEmail: "root@dummy.com",
+	})
+	s.Require().NoError(err)
+
+	s.Require().NoError(mailer.Send(s.message(), s.message()))
+
+	s.Len(s.captured(), 2)
+}
+
+func (s *transportTestSuite) TestUnknownTransport() {
+	_, err := NewTransport(config.MailConfig{Transport: "pigeon"})
+	s.Error(err)
+}
+
+func TestTransports(t *testing.T) {
+	suite.Run(t, new(transportTestSuite))
+}
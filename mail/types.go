@@ -25,3 +25,23 @@ type IMailer interface {
 type IDialer interface {
 	DialAndSend(...*gomail.Message) error
 }
+
+// ISendDialer is an IDialer which can also open a connection for sending
+// prerendered messages, it is needed for DKIM signing. gomail.Dialer
+// implements it
+type ISendDialer interface {
+	IDialer
+	Dial() (gomail.SendCloser, error)
+}
+
+// Sender is the name and address mails are sent from
+type Sender struct {
+	Name  string
+	Email string
+}
+
+// Transport delivers messages to a mail provider on behalf of a sender,
+// it lets the Mailer send through SMTP or an HTTP API interchangeably
+type Transport interface {
+	Deliver(sender Sender, msgs ...SendableMessage) error
+}
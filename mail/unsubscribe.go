@@ -0,0 +1,121 @@
+package mail
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"net/url"
+	"strings"
+	"time"
+)
+
+// SetListUnsubscribe sets the List-Unsubscribe header of the message to
+// the given https url and mailto address, either may be empty. When a url
+// is given the List-Unsubscribe-Post header is set too so mail clients
+// can unsubscribe with a single click as described in RFC 8058
+func (m *Message) SetListUnsubscribe(unsubscribeURL string, mailto string) {
+	if m.Headers == nil {
+		m.Headers = make(map[string][]string)
+	}
+	targets := []string{}
+	if unsubscribeURL != "" {
+		targets = append(targets, "<"+unsubscribeURL+">")
+		m.Headers["List-Unsubscribe-Post"] = []string{"List-Unsubscribe=One-Click"}
+	}
+	if mailto != "" {
+		targets = append(targets, "<mailto:"+strings.TrimPrefix(mailto, "mailto:")+">")
+	}
+	if len(targets) > 0 {
+		m.Headers["List-Unsubscribe"] = []string{strings.Join(targets, ", ")}
+	}
+}
+
+var (
+	// ErrInvalidUnsubscribeToken is returned for malformed or forged tokens
+	ErrInvalidUnsubscribeToken = errors.New("mail: invalid unsubscribe token")
+	// ErrUnsubscribeTokenExpired is returned for tokens older than the
+	// max age of the signer
+	ErrUnsubscribeTokenExpired = errors.New("mail: unsubscribe token expired")
+)
+
+// UnsubscribeClaims identifies who is unsubscribing from which list
+type UnsubscribeClaims struct {
+	Email    string
+	List     string
+	IssuedAt time.Time
+}
+
+type unsubscribePayload struct {
+	Email    string `json:"e"`
+	List     string `json:"l"`
+	IssuedAt int64  `json:"iat"`
+}
+
+// UnsubscribeSigner creates and verifies HMAC signed unsubscribe tokens,
+// so unsubscribe links cannot be forged for other addresses
+type UnsubscribeSigner struct {
+	key    []byte
+	maxAge time.Duration
+	now    func() time.Time
+}
+
+// NewUnsubscribeSigner constructs an UnsubscribeSigner, tokens older than
+// maxAge are rejected. A zero maxAge accepts tokens of any age
+func NewUnsubscribeSigner(key string, maxAge time.Duration) *UnsubscribeSigner {
+	return &UnsubscribeSigner{key: []byte(key), maxAge: maxAge, now: time.Now}
+}
+
+// Token returns a signed token unsubscribing email from list
+func (s *UnsubscribeSigner) Token(email string, list string) string {
+	payload, _ := json.Marshal(unsubscribePayload{
+		Email: email, List: list, IssuedAt: s.now().Unix()})
+	encoded := base64.RawURLEncoding.EncodeToString(payload)
+	return encoded + "." + s.sign(encoded)
+}
+
+// URL returns baseURL with a token unsubscribing email from list
+// added as the token query parameter
+func (s *UnsubscribeSigner) URL(baseURL string, email string, list string) (string, error) {
+	parsed, err := url.Parse(baseURL)
+	if err != nil {
+		return "", err
+	}
+	query := parsed.Query()
+	query.Set("token", s.Token(email, list))
+	parsed.RawQuery = query.Encode()
+	return parsed.String(), nil
+}
+
+// Verify checks the signature and age of token and returns its claims
+func (s *UnsubscribeSigner) Verify(token string) (UnsubscribeClaims, error) {
+	parts := strings.Split(token, ".")
+	if len(parts) != 2 {
+		return UnsubscribeClaims{}, ErrInvalidUnsubscribeToken
+	}
+	if !hmac.Equal([]byte(parts[1]), []byte(s.sign(parts[0]))) {
+		return UnsubscribeClaims{}, ErrInvalidUnsubscribeToken
+	}
+	raw, err := base64.RawURLEncoding.DecodeString(parts[0])
+	if err != nil {
+		return UnsubscribeClaims{}, ErrInvalidUnsubscribeToken
+	}
+	var payload unsubscribePayload
+	if err := json.Unmarshal(raw, &payload); err != nil {
+		return UnsubscribeClaims{}, ErrInvalidUnsubscribeToken
+	}
+	claims := UnsubscribeClaims{
+		Email: payload.Email, List: payload.List,
+		IssuedAt: time.Unix(payload.IssuedAt, 0)}
+	if s.maxAge > 0 && s.now().Sub(claims.IssuedAt) > s.maxAge {
+		return UnsubscribeClaims{}, ErrUnsubscribeTokenExpired
+	}
+	return claims, nil
+}
+
+func (s *UnsubscribeSigner) sign(payload string) string {
+	mac := hmac.New(sha256.New, s.key)
+	mac.Write([]byte(payload))
+	return base64.RawURLEncoding.EncodeToString(mac.Sum(nil))
+}
@@ -0,0 +1,84 @@
+package mail
+
+import (
+	"net/url"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/suite"
+)
+
+type unsubscribeTestSuite struct {
+	suite.Suite
+	signer *UnsubscribeSigner
+	now    time.Time
+}
+
+func (s *unsubscribeTestSuite) SetupTest() {
+	s.now = time.Date(2023, 5, 1, 12, 0, 0, 0, time.UTC)
+	s.signer = NewUnsubscribeSigner("secret", 24*time.Hour)
+	s.signer.now = func() time.Time { return s.now }
+}
+
+func (s *unsubscribeTestSuite) TestTokenRoundTrip() {
+	claims, err := s.signer.Verify(s.signer.Token("me@me.com", "digest"))
+
+	s.NoError(err)
+	s.Equal("me@me.com", claims.Email)
+	s.Equal("digest", claims.List)
+	s.Equal(s.now, claims.IssuedAt.UTC())
+}
+
+func (s *unsubscribeTestSuite) TestForgedTokenRejected() {
+	token := NewUnsubscribeSigner("other secret", 0).Token("me@me.com", "digest")
+
+	_, err := s.signer.Verify(token)
+
+	s.ErrorIs(err, ErrInvalidUnsubscribeToken)
+}
+
+func (s *unsubscribeTestSuite) TestExpiredTokenRejected() {
+	token := s.signer.Token("me@me.com", "digest")
+	s.now = s.now.Add(25 * time.Hour)
+
+	_, err := s.signer.Verify(token)
+
+	s.ErrorIs(err, ErrUnsubscribeTokenExpired)
+}
+
+func (s *unsubscribeTestSuite) TestURLCarriesToken() {
+	link, err := s.signer.URL("https://app.dummy.com/unsubscribe?src=mail", "me@me.com", "digest")
+	s.Require().NoError(err)
+
+	parsed, _ := url.Parse(link)
+	s.Equal("mail", parsed.Query().Get("src"))
+	_, err = s.signer.Verify(parsed.Query().Get("token"))
+	s.NoError(err)
+}
+
+func (s *unsubscribeTestSuite) TestListUnsubscribeHeaders() {
+	inner := NewCapturingMailer()
+	msg := InitializeMessage()
+	msg.TxtMsg = "Your digest"
+	msg.Recipients = append(msg.Recipients, "me@me.com")
+	msg.SetListUnsubscribe("https://app.dummy.com/unsubscribe?token=abc", "unsubscribe@dummy.com")
+
+	inner.Send(msg)
+
+	last, _ := inner.Last()
+	s.Contains(last.Raw, "List-Unsubscribe: <https://app.dummy.com/unsubscribe?token=abc>,")
+	s.Contains(last.Raw, "<mailto:unsubscribe@dummy.com>")
+	s.Contains(last.Raw, "List-Unsubscribe-Post: List-Unsubscribe=One-Click")
+}
+
+func (s *unsubscribeTestSuite) TestListUnsubscribeOnZeroValueMessage() {
+	var msg Message
+
+	msg.SetListUnsubscribe("", "unsubscribe@dummy.com")
+
+	s.Equal([]string{"<mailto:unsubscribe@dummy.com>"}, msg.Headers["List-Unsubscribe"])
+}
+
+func TestUnsubscribe(t *testing.T) {
+	suite.Run(t, new(unsubscribeTestSuite))
+}
@@ -14,16 +14,19 @@ type UserRepository interface {
 	FindAuthUser(username interface{}) (interface{}, errors.ApplicationError)
 }
 
-type JWTAuthMiddleware struct {
-	authAdapter services.JWTAuthService
+// JWTAuthMiddleware authenticates requests bearing a token whose custom
+// claims are C, the claims are stored in the context with the user.
+// Revoked tokens are rejected when the verifier is a RevocationChecker
+type JWTAuthMiddleware[C any] struct {
+	authAdapter services.TokenVerifier[C]
 	userRepo    UserRepository
 }
 
-func NewJWTAuthMiddleware(authService services.JWTAuthService, userRepo UserRepository) JWTAuthMiddleware {
-	return JWTAuthMiddleware{authAdapter: authService, userRepo: userRepo}
+func NewJWTAuthMiddleware[C any](authService services.TokenVerifier[C], userRepo UserRepository) JWTAuthMiddleware[C] {
+	return JWTAuthMiddleware[C]{authAdapter: authService, userRepo: userRepo}
 }
 
-func (m JWTAuthMiddleware) Authorize(c *gin.Context) {
+func (m JWTAuthMiddleware[C]) Authorize(c *gin.Context) {
 	authorization, ok := c.Request.Header["Authorization"]
 	if !ok {
 		errorResponse(c, errors.UnauthorizedError("Unauthorized"))
@@ -40,27 +43,42 @@ func (m JWTAuthMiddleware) Authorize(c *gin.Context) {
 		errorResponse(c, err)
 		return
 	}
-	if claims["use"] != types.AuthTokenKey {
+	if claims.Use != types.AuthTokenKey {
 		errorResponse(c, errors.UnauthorizedError(""))
 		return
 	}
+	if checker, ok := m.authAdapter.(services.RevocationChecker); ok {
+		revoked, checkErr := checker.Revoked(claims.RegisteredClaims)
+		if checkErr != nil {
+			errorResponse(c, errors.InternalServerError("Could not check token revocation"))
+			return
+		}
+		if revoked {
+			errorResponse(c, errors.UnauthorizedError("Token has been revoked"))
+			return
+		}
+	}
 
-	uid := claims[config.UserIdClaim]
-	user, err := m.userRepo.FindAuthUser(uid)
+	user, err := m.userRepo.FindAuthUser(claims.UserID)
 	if err != nil {
 		errorResponse(c, errors.UnauthorizedError("User not found"))
 		return
 	}
 
 	c.Set(config.AuthUserContextKey, user)
+	c.Set(config.AuthClaimsContextKey, claims)
 	c.Next()
 }
 
 func errorResponse(ctx *gin.Context, err errors.ApplicationError) {
 	code, message := err.Resolve()
-	ctx.JSON(code, gin.H{
+	body := gin.H{
 		"status": false,
 		"error":  message,
-	})
+	}
+	if reason, ok := errors.ErrorCodeOf(err); ok {
+		body["code"] = reason
+	}
+	ctx.JSON(code, body)
 	ctx.Abort()
 }
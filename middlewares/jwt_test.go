@@ -0,0 +1,111 @@
+package middlewares
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/dino16m/golearn-core/config"
+	"github.com/dino16m/golearn-core/errors"
+	"github.com/dino16m/golearn-core/services"
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/suite"
+)
+
+type staticUserRepository struct{}
+
+func (staticUserRepository) FindAuthUser(username interface{}) (interface{}, errors.ApplicationError) {
+	return username, nil
+}
+
+// brokenRevoker fails every revocation check
+type brokenRevoker struct {
+	*services.InMemoryTokenBlacklister
+}
+
+func (brokenRevoker) IsRevoked(jti string, userID string, issuedAt time.Time) (bool, error) {
+	return false, fmt.Errorf("revocation store is down")
+}
+
+type jwtAuthMiddlewareTestSuite struct {
+	suite.Suite
+	options     config.JwtOptions
+	blacklister *services.InMemoryTokenBlacklister
+	service     services.JWTAuthService[services.JWTClaims]
+}
+
+func (s *jwtAuthMiddlewareTestSuite) SetupTest() {
+	gin.SetMode(gin.TestMode)
+	s.options = config.JwtOptions{Key: "secret", Timeout: time.Minute, MaxRefresh: time.Hour}
+	s.blacklister = services.NewInMemoryTokenBlacklister()
+	service, err := services.NewJWTAuthService[services.JWTClaims](s.options, s.blacklister)
+	s.Require().NoError(err)
+	s.service = service
+}
+
+func (s *jwtAuthMiddlewareTestSuite) TearDownTest() {
+	s.blacklister.Close()
+}
+
+func (s *jwtAuthMiddlewareTestSuite) login() (string, services.Claims[services.JWTClaims]) {
+	_, auth, err := s.service.GetTokenPair(services.Claims[services.JWTClaims]{
+		RegisteredClaims: services.RegisteredClaims{UserID: "user-1"},
+	}, services.Client{})
+	s.Require().NoError(err)
+	claims, appErr := s.service.GetClaim(auth)
+	s.Require().Nil(appErr)
+	return auth, claims
+}
+
+func (s *jwtAuthMiddlewareTestSuite) authorize(verifier services.TokenVerifier[services.JWTClaims], token string) *httptest.ResponseRecorder {
+	router := gin.New()
+	router.GET("/me", NewJWTAuthMiddleware[services.JWTClaims](verifier, staticUserRepository{}).Authorize,
+		func(c *gin.Context) { c.Status(http.StatusOK) })
+	req := httptest.NewRequest(http.MethodGet, "/me", nil)
+	req.Header.Set("Authorization", "Bearer "+token)
+	res := httptest.NewRecorder()
+	router.ServeHTTP(res, req)
+	return res
+}
+
+func (s *jwtAuthMiddlewareTestSuite) TestValidTokenPasses() {
+	token, _ := s.login()
+
+	s.Equal(http.StatusOK, s.authorize(s.service, token).Code)
+}
+
+func (s *jwtAuthMiddlewareTestSuite) TestRevokedTokenIsRejected() {
+	token, claims := s.login()
+	s.Require().Nil(s.service.Logout(claims))
+
+	s.Equal(http.StatusUnauthorized, s.authorize(s.service, token).Code)
+}
+
+func (s *jwtAuthMiddlewareTestSuite) TestTokenIssuedBeforeWatermarkIsRejected() {
+	token, claims := s.login()
+	s.Require().Nil(s.service.LogoutAll(claims))
+
+	s.Equal(http.StatusUnauthorized, s.authorize(s.service, token).Code)
+}
+
+func (s *jwtAuthMiddlewareTestSuite) TestTokenIssuedAfterWatermarkPasses() {
+	_, claims := s.login()
+	s.Require().Nil(s.service.LogoutAll(claims))
+	token, _ := s.login()
+
+	s.Equal(http.StatusOK, s.authorize(s.service, token).Code)
+}
+
+func (s *jwtAuthMiddlewareTestSuite) TestRevocationCheckErrorFails() {
+	service, err := services.NewJWTAuthService[services.JWTClaims](s.options, brokenRevoker{s.blacklister})
+	s.Require().NoError(err)
+	token, _ := s.login()
+
+	s.Equal(http.StatusInternalServerError, s.authorize(service, token).Code)
+}
+
+func TestJWTAuthMiddleware(t *testing.T) {
+	suite.Run(t, new(jwtAuthMiddlewareTestSuite))
+}
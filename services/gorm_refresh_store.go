@@ -0,0 +1,287 @@
+package services
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// RefreshSession is the database row of a refresh token family
+type RefreshSession struct {
+	ID         uint   `gorm:"primaryKey"`
+	Family     string `gorm:"size:64;uniqueIndex"`
+	CurrentJTI string `gorm:"size:64"`
+	UserID     string `gorm:"size:191;index"`
+	UserAgent  string
+	IP         string `gorm:"size:64"`
+	CreatedAt  time.Time
+	LastUsedAt time.Time
+	ExpiresAt  time.Time
+	RevokedAt  *time.Time
+
+	// PreviousJTI is the token rotated last, it may be replayed for the
+	// pair issued by the rotation until GraceUntil
+	PreviousJTI   string `gorm:"size:64"`
+	ReplayRefresh string
+	ReplayAuth    string
+	GraceUntil    *time.Time
+}
+
+// RevokedToken is the database row of a token revoked before it expires
+type RevokedToken struct {
+	JTI       string    `gorm:"size:64;primaryKey"`
+	ExpiresAt time.Time `gorm:"index"`
+}
+
+// TokenWatermark is the database row of the time before which the tokens
+// of a user are revoked. IssuedBefore is in unix nanoseconds as not every
+// database keeps the fraction of a second of a time
+type TokenWatermark struct {
+	UserID       string `gorm:"size:191;primaryKey"`
+	IssuedBefore int64
+	ExpiresAt    time.Time `gorm:"index"`
+}
+
+// clearedReplay are the updates dropping the pair kept for a replay, so
+// live tokens are not stored past their grace period
+var clearedReplay = map[string]interface{}{
+	"previous_jti":   "",
+	"replay_refresh": "",
+	"replay_auth":    "",
+	"grace_until":    nil,
+}
+
+func (s RefreshSession) session() Session {
+	return Session{
+		Family:     s.Family,
+		UserID:     s.UserID,
+		UserAgent:  s.UserAgent,
+		IP:         s.IP,
+		CreatedAt:  s.CreatedAt,
+		LastUsedAt: s.LastUsedAt,
+		ExpiresAt:  s.ExpiresAt,
+	}
+}
+
+// GormRefreshStore is a SessionStore and TokenRevoker persisting refresh
+// token families and revocations with GORM. Only the current refresh token
+// of a family is valid
+type GormRefreshStore struct {
+	db  *gorm.DB
+	now func() time.Time
+}
+
+// NewGormRefreshStore constructs a GormRefreshStore. The token pair kept
+// for a replay during the RefreshGracePeriod is cleared when the family is
+// next used after the grace period, PurgeExpiredReplays and
+// PurgeRevokedTokens must be run periodically to clear the rows of
+// families which are not used again
+func NewGormRefreshStore(db *gorm.DB) *GormRefreshStore {
+	return &GormRefreshStore{db: db, now: time.Now}
+}
+
+// Migrate creates or updates the tables of the store
+func (store *GormRefreshStore) Migrate() error {
+	return store.db.AutoMigrate(&RefreshSession{}, &RevokedToken{}, &TokenWatermark{})
+}
+
+func (store *GormRefreshStore) SaveSession(session Session, jti string, expiresAt time.Time) error {
+	now := store.now()
+	return store.db.Transaction(func(tx *gorm.DB) error {
+		var existing RefreshSession
+		result := tx.Where("family = ?", session.Family).Limit(1).Find(&existing)
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return tx.Create(&RefreshSession{
+				Family:     session.Family,
+				CurrentJTI: jti,
+				UserID:     session.UserID,
+				UserAgent:  session.UserAgent,
+				IP:         session.IP,
+				CreatedAt:  now,
+				LastUsedAt: now,
+				ExpiresAt:  expiresAt,
+			}).Error
+		}
+		updates := map[string]interface{}{
+			"current_jti":  jti,
+			"last_used_at": now,
+			"expires_at":   expiresAt,
+		}
+		if session.UserAgent != "" {
+			updates["user_agent"] = session.UserAgent
+		}
+		if session.IP != "" {
+			updates["ip"] = session.IP
+		}
+		return tx.Model(&existing).Updates(updates).Error
+	})
+}
+
+func (store *GormRefreshStore) BlackListFamily(family string, expiresAt time.Time) error {
+	return store.db.Model(&RefreshSession{}).
+		Where("family = ? AND revoked_at IS NULL", family).
+		Update("revoked_at", store.now()).Error
+}
+
+// SetCurrentJTI records jti as the current token of a family which has no
+// session information, SaveSession is used when it is known
+func (store *GormRefreshStore) SetCurrentJTI(family string, jti string, expiresAt time.Time) error {
+	return store.SaveSession(Session{Family: family}, jti, expiresAt)
+}
+
+// ValidateJTI also clears the pair kept for a replay of the family once
+// its grace period is over
+func (store *GormRefreshStore) ValidateJTI(family string, jti string) (bool, error) {
+	now := store.now()
+	if err := store.clearExpiredReplay(family, now); err != nil {
+		return false, err
+	}
+	var count int64
+	err := store.db.Model(&RefreshSession{}).
+		Where("family = ? AND current_jti = ? AND revoked_at IS NULL AND expires_at > ?", family, jti, now).
+		Count(&count).Error
+	return count > 0, err
+}
+
+func (store *GormRefreshStore) clearExpiredReplay(family string, now time.Time) error {
+	return store.db.Model(&RefreshSession{}).
+		Where("family = ? AND grace_until <= ?", family, now).
+		Updates(clearedReplay).Error
+}
+
+// RotateJTI swaps the current token with a conditional update so only one
+// of concurrent rotations matches the old token
+func (store *GormRefreshStore) RotateJTI(family string, oldJTI string, newJTI string, expiresAt time.Time, pair TokenPair, graceUntil time.Time) (bool, error) {
+	now := store.now()
+	var grace *time.Time
+	if !graceUntil.IsZero() {
+		grace = &graceUntil
+	} else {
+		pair = TokenPair{}
+	}
+	result := store.db.Model(&RefreshSession{}).
+		Where("family = ? AND current_jti = ? AND revoked_at IS NULL AND expires_at > ?", family, oldJTI, now).
+		Updates(map[string]interface{}{
+			"current_jti":    newJTI,
+			"last_used_at":   now,
+			"expires_at":     expiresAt,
+			"previous_jti":   oldJTI,
+			"replay_refresh": pair.Refresh,
+			"replay_auth":    pair.Auth,
+			"grace_until":    grace,
+		})
+	return result.RowsAffected == 1, result.Error
+}
+
+// Replay also clears the pair of the family once its grace period is over
+func (store *GormRefreshStore) Replay(family string, jti string) (TokenPair, bool, error) {
+	now := store.now()
+	if err := store.clearExpiredReplay(family, now); err != nil {
+		return TokenPair{}, false, err
+	}
+	var session RefreshSession
+	result := store.db.
+		Where("family = ? AND previous_jti = ? AND revoked_at IS NULL AND grace_until > ?", family, jti, now).
+		Limit(1).Find(&session)
+	if result.Error != nil || result.RowsAffected == 0 {
+		return TokenPair{}, false, result.Error
+	}
+	return TokenPair{Refresh: session.ReplayRefresh, Auth: session.ReplayAuth}, true, nil
+}
+
+// PurgeExpiredReplays clears the pairs kept for replays whose grace period
+// is over, it is meant to run periodically like PurgeRevokedTokens
+func (store *GormRefreshStore) PurgeExpiredReplays() error {
+	return store.db.Model(&RefreshSession{}).
+		Where("grace_until <= ?", store.now()).
+		Updates(clearedReplay).Error
+}
+
+func (store *GormRefreshStore) ListSessions(userID string) ([]Session, error) {
+	var rows []RefreshSession
+	err := store.db.
+		Where("user_id = ? AND revoked_at IS NULL AND expires_at > ?", userID, store.now()).
+		Order("last_used_at DESC").
+		Find(&rows).Error
+	if err != nil {
+		return nil, err
+	}
+	sessions := make([]Session, len(rows))
+	for i, row := range rows {
+		sessions[i] = row.session()
+	}
+	return sessions, nil
+}
+
+func (store *GormRefreshStore) RevokeSession(userID string, family string) (bool, error) {
+	result := store.db.Model(&RefreshSession{}).
+		Where("user_id = ? AND family = ? AND revoked_at IS NULL", userID, family).
+		Update("revoked_at", store.now())
+	return result.RowsAffected > 0, result.Error
+}
+
+func (store *GormRefreshStore) RevokeAllSessions(userID string) error {
+	return store.db.Model(&RefreshSession{}).
+		Where("user_id = ? AND revoked_at IS NULL", userID).
+		Update("revoked_at", store.now()).Error
+}
+
+func (store *GormRefreshStore) RevokeToken(jti string, expiresAt time.Time) error {
+	return store.db.Clauses(clause.OnConflict{
+		Columns:   []clause.Column{{Name: "jti"}},
+		DoUpdates: clause.AssignmentColumns([]string{"expires_at"}),
+	}).Create(&RevokedToken{JTI: jti, ExpiresAt: expiresAt}).Error
+}
+
+// RevokeUserTokens upserts the watermark in a single statement keeping the
+// later of the stored and the given watermark, so concurrent calls neither
+// conflict nor lower it
+func (store *GormRefreshStore) RevokeUserTokens(userID string, issuedBefore time.Time, expiresAt time.Time) error {
+	return store.db.Clauses(clause.OnConflict{
+		Columns: []clause.Column{{Name: "user_id"}},
+		DoUpdates: clause.Assignments(map[string]interface{}{
+			"issued_before": laterOf("issued_before"),
+			"expires_at":    laterOf("expires_at"),
+		}),
+	}).Create(&TokenWatermark{UserID: userID, IssuedBefore: issuedBefore.UnixNano(), ExpiresAt: expiresAt}).Error
+}
+
+// laterOf is the value of column in an upsert, the greater of the stored
+// and the inserted value
+func laterOf(column string) clause.Expr {
+	stored := clause.Column{Table: clause.CurrentTable, Name: column}
+	inserted := clause.Column{Table: "excluded", Name: column}
+	return gorm.Expr("CASE WHEN ? > ? THEN ? ELSE ? END", inserted, stored, inserted, stored)
+}
+
+func (store *GormRefreshStore) IsRevoked(jti string, userID string, issuedAt time.Time) (bool, error) {
+	if jti != "" {
+		var count int64
+		err := store.db.Model(&RevokedToken{}).
+			Where("jti = ? AND expires_at > ?", jti, store.now()).
+			Count(&count).Error
+		if err != nil || count > 0 {
+			return count > 0, err
+		}
+	}
+	var watermark TokenWatermark
+	result := store.db.Where("user_id = ?", userID).Limit(1).Find(&watermark)
+	if result.Error != nil || result.RowsAffected == 0 {
+		return false, result.Error
+	}
+	return beforeWatermark(issuedAt, time.Unix(0, watermark.IssuedBefore)), nil
+}
+
+// PurgeRevokedTokens deletes the revocations of tokens which have expired
+// and the watermarks every token issued before has expired for
+func (store *GormRefreshStore) PurgeRevokedTokens() error {
+	now := store.now()
+	if err := store.db.Where("expires_at <= ?", now).Delete(&RevokedToken{}).Error; err != nil {
+		return err
+	}
+	return store.db.Where("expires_at <= ?", now).Delete(&TokenWatermark{}).Error
+}
@@ -0,0 +1,297 @@
+package services
+
+import (
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/dino16m/golearn-core/config"
+	"github.com/glebarez/sqlite"
+	"github.com/stretchr/testify/suite"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+)
+
+type gormRefreshStoreTestSuite struct {
+	suite.Suite
+	store *GormRefreshStore
+	now   time.Time
+}
+
+func (s *gormRefreshStoreTestSuite) SetupTest() {
+	db, err := gorm.Open(sqlite.Open("file:"+getJTI()+"?mode=memory&cache=shared"), &gorm.Config{Logger: logger.Discard})
+	s.Require().NoError(err)
+	s.store = NewGormRefreshStore(db)
+	s.now = time.Now().Truncate(time.Second)
+	s.store.now = func() time.Time { return s.now }
+	s.Require().NoError(s.store.Migrate())
+}
+
+func (s *gormRefreshStoreTestSuite) save(userID string, family string, jti string) {
+	s.Require().NoError(s.store.SaveSession(Session{
+		Family: family, UserID: userID, UserAgent: "Firefox", IP: "10.0.0.1",
+	}, jti, s.now.Add(time.Hour)))
+}
+
+func (s *gormRefreshStoreTestSuite) TestOnlyCurrentJTIIsValid() {
+	s.save("user-1", "family", "jti-1")
+	s.save("user-1", "family", "jti-2")
+
+	valid, err := s.store.ValidateJTI("family", "jti-1")
+	s.NoError(err)
+	s.False(valid)
+	valid, err = s.store.ValidateJTI("family", "jti-2")
+	s.NoError(err)
+	s.True(valid)
+}
+
+func (s *gormRefreshStoreTestSuite) TestUnknownFamilyIsInvalid() {
+	valid, err := s.store.ValidateJTI("family", "jti")
+
+	s.NoError(err)
+	s.False(valid)
+}
+
+func (s *gormRefreshStoreTestSuite) TestSetCurrentJTIWithoutSession() {
+	s.Require().NoError(s.store.SetCurrentJTI("family", "jti-1", s.now.Add(time.Hour)))
+	s.Require().NoError(s.store.SetCurrentJTI("family", "jti-2", s.now.Add(time.Hour)))
+
+	old, _ := s.store.ValidateJTI("family", "jti-1")
+	current, _ := s.store.ValidateJTI("family", "jti-2")
+
+	s.False(old)
+	s.True(current)
+}
+
+func (s *gormRefreshStoreTestSuite) TestBlacklistedFamily() {
+	s.save("user-1", "family", "jti-1")
+	s.Require().NoError(s.store.BlackListFamily("family", s.now.Add(time.Hour)))
+
+	valid, _ := s.store.ValidateJTI("family", "jti-1")
+
+	s.False(valid)
+}
+
+func (s *gormRefreshStoreTestSuite) TestExpiredSessionIsInvalid() {
+	s.save("user-1", "family", "jti-1")
+	s.now = s.now.Add(2 * time.Hour)
+
+	valid, _ := s.store.ValidateJTI("family", "jti-1")
+	sessions, _ := s.store.ListSessions("user-1")
+
+	s.False(valid)
+	s.Empty(sessions)
+}
+
+func (s *gormRefreshStoreTestSuite) TestListSessions() {
+	s.save("user-1", "older", "jti-1")
+	s.now = s.now.Add(time.Minute)
+	s.save("user-1", "newer", "jti-2")
+	s.save("user-2", "other", "jti-3")
+
+	sessions, err := s.store.ListSessions("user-1")
+
+	s.Require().NoError(err)
+	s.Require().Len(sessions, 2)
+	s.Equal("newer", sessions[0].Family)
+	s.Equal("older", sessions[1].Family)
+	s.Equal("Firefox", sessions[0].UserAgent)
+	s.Equal("10.0.0.1", sessions[0].IP)
+}
+
+func (s *gormRefreshStoreTestSuite) TestRefreshUpdatesLastUsed() {
+	s.save("user-1", "family", "jti-1")
+	created := s.now
+	s.now = s.now.Add(time.Minute)
+	s.save("user-1", "family", "jti-2")
+
+	sessions, _ := s.store.ListSessions("user-1")
+
+	s.Require().Len(sessions, 1)
+	s.True(sessions[0].CreatedAt.Equal(created))
+	s.True(sessions[0].LastUsedAt.Equal(s.now))
+}
+
+func (s *gormRefreshStoreTestSuite) storedReplay(family string) RefreshSession {
+	var session RefreshSession
+	s.Require().NoError(s.store.db.Where("family = ?", family).First(&session).Error)
+	return session
+}
+
+func (s *gormRefreshStoreTestSuite) TestReplayClearsPairAfterGrace() {
+	s.save("user-1", "family", "jti-1")
+	pair := TokenPair{Refresh: "refresh", Auth: "auth"}
+	_, err := s.store.RotateJTI("family", "jti-1", "jti-2", s.now.Add(time.Hour), pair, s.now.Add(time.Minute))
+	s.Require().NoError(err)
+	s.now = s.now.Add(time.Minute)
+
+	_, ok, err := s.store.Replay("family", "jti-1")
+
+	s.NoError(err)
+	s.False(ok)
+	stored := s.storedReplay("family")
+	s.Empty(stored.ReplayRefresh)
+	s.Empty(stored.ReplayAuth)
+	s.Nil(stored.GraceUntil)
+}
+
+func (s *gormRefreshStoreTestSuite) TestValidateJTIClearsPairAfterGrace() {
+	s.save("user-1", "family", "jti-1")
+	pair := TokenPair{Refresh: "refresh", Auth: "auth"}
+	_, err := s.store.RotateJTI("family", "jti-1", "jti-2", s.now.Add(time.Hour), pair, s.now.Add(time.Minute))
+	s.Require().NoError(err)
+	s.now = s.now.Add(time.Minute)
+
+	valid, err := s.store.ValidateJTI("family", "jti-2")
+
+	s.NoError(err)
+	s.True(valid)
+	stored := s.storedReplay("family")
+	s.Empty(stored.ReplayRefresh)
+	s.Empty(stored.ReplayAuth)
+	s.Nil(stored.GraceUntil)
+}
+
+func (s *gormRefreshStoreTestSuite) TestPurgeExpiredReplays() {
+	s.save("user-1", "expired", "jti-1")
+	s.save("user-1", "within", "jti-3")
+	pair := TokenPair{Refresh: "refresh", Auth: "auth"}
+	s.store.RotateJTI("expired", "jti-1", "jti-2", s.now.Add(time.Hour), pair, s.now.Add(time.Minute))
+	s.store.RotateJTI("within", "jti-3", "jti-4", s.now.Add(time.Hour), pair, s.now.Add(time.Hour))
+	s.now = s.now.Add(time.Minute)
+
+	s.Require().NoError(s.store.PurgeExpiredReplays())
+
+	s.Empty(s.storedReplay("expired").ReplayAuth)
+	s.Equal("auth", s.storedReplay("within").ReplayAuth)
+	replayed, ok, _ := s.store.Replay("within", "jti-3")
+	s.True(ok)
+	s.Equal(pair, replayed)
+}
+
+func (s *gormRefreshStoreTestSuite) TestRevokeSession() {
+	s.save("user-1", "family", "jti-1")
+
+	revoked, err := s.store.RevokeSession("user-2", "family")
+	s.NoError(err)
+	s.False(revoked)
+	revoked, err = s.store.RevokeSession("user-1", "family")
+	s.NoError(err)
+	s.True(revoked)
+
+	valid, _ := s.store.ValidateJTI("family", "jti-1")
+	s.False(valid)
+}
+
+func (s *gormRefreshStoreTestSuite) TestRevokeAllSessions() {
+	s.save("user-1", "first", "jti-1")
+	s.save("user-1", "second", "jti-2")
+	s.save("user-2", "other", "jti-3")
+
+	s.Require().NoError(s.store.RevokeAllSessions("user-1"))
+
+	sessions, _ := s.store.ListSessions("user-1")
+	s.Empty(sessions)
+	sessions, _ = s.store.ListSessions("user-2")
+	s.Len(sessions, 1)
+}
+
+func (s *gormRefreshStoreTestSuite) TestSessionLifetimeWithoutAuthTimeCountsFromFamilyCreation() {
+	options := config.JwtOptions{Key: "secret", Timeout: time.Minute, MaxRefresh: time.Hour, MaxSessionLifetime: time.Hour}
+	service, _ := NewJWTAuthService[JWTClaims](options, s.store)
+	s.now = time.Now().Add(-30 * time.Minute).Truncate(time.Second)
+	claims := userClaims("user-1")
+	claims.Family = "family"
+	refresh := mustSign(service.GetRefreshToken(claims))
+
+	pair, err := service.RefreshToken(refresh, Client{})
+
+	s.Require().Nil(err)
+	refreshed, _ := service.GetClaim(pair.Refresh)
+	s.Equal(s.now.Unix(), refreshed.AuthTime)
+	s.Equal(s.now.Add(time.Hour).Unix(), refreshed.ExpiresAt)
+}
+
+func (s *gormRefreshStoreTestSuite) TestSessionLifetimeWithoutAuthTimeExpiresWithFamily() {
+	options := config.JwtOptions{Key: "secret", Timeout: time.Minute, MaxRefresh: 3 * time.Hour, MaxSessionLifetime: time.Hour}
+	service, _ := NewJWTAuthService[JWTClaims](options, s.store)
+	s.now = time.Now().Add(-2 * time.Hour)
+	claims := userClaims("user-1")
+	claims.Family = "family"
+	refresh := mustSign(service.GetRefreshToken(claims))
+
+	_, err := service.RefreshToken(refresh, Client{})
+
+	s.Equal(ErrSessionExpired, err)
+}
+
+func (s *gormRefreshStoreTestSuite) TestConcurrentWatermarksKeepTheLatest() {
+	latest := s.now.Add(10 * time.Second)
+	var wg sync.WaitGroup
+	errs := make(chan error, 10)
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			errs <- s.store.RevokeUserTokens("user-1", latest.Add(-time.Duration(i)*time.Second), s.now.Add(time.Hour))
+		}(i)
+	}
+	wg.Wait()
+	close(errs)
+
+	for err := range errs {
+		s.NoError(err)
+	}
+	atLatest, _ := s.store.IsRevoked("", "user-1", latest)
+	s.True(atLatest)
+	after, _ := s.store.IsRevoked("", "user-1", latest.Add(time.Millisecond))
+	s.False(after)
+}
+
+func (s *gormRefreshStoreTestSuite) TestPurgeRevokedTokensDropsExpiredWatermarks() {
+	s.Require().NoError(s.store.RevokeUserTokens("old", s.now, s.now.Add(time.Minute)))
+	s.Require().NoError(s.store.RevokeUserTokens("new", s.now, s.now.Add(time.Hour)))
+	s.now = s.now.Add(30 * time.Minute)
+
+	s.Require().NoError(s.store.PurgeRevokedTokens())
+
+	var userIDs []string
+	s.Require().NoError(s.store.db.Model(&TokenWatermark{}).Pluck("user_id", &userIDs).Error)
+	s.Equal([]string{"new"}, userIDs)
+}
+
+func (s *gormRefreshStoreTestSuite) TestServiceRecordsSessions() {
+	options := config.JwtOptions{Key: "secret", Timeout: time.Minute, MaxRefresh: time.Hour}
+	service, _ := NewJWTAuthService[JWTClaims](options, s.store)
+	client := Client{UserAgent: "Safari", IP: "10.0.0.2"}
+	refresh, _, err := service.GetTokenPair(userClaims("user-1"), client)
+	s.Require().NoError(err)
+
+	pair, appErr := service.RefreshToken(refresh, client)
+	s.Require().Nil(appErr)
+	_, appErr = service.RefreshToken(refresh, client)
+	s.NotNil(appErr)
+
+	sessions, _ := s.store.ListSessions("user-1")
+	s.Empty(sessions)
+	_, appErr = service.RefreshToken(pair.Refresh, client)
+	s.NotNil(appErr)
+}
+
+func (s *gormRefreshStoreTestSuite) TestSessionListedAfterLogin() {
+	options := config.JwtOptions{Key: "secret", Timeout: time.Minute, MaxRefresh: time.Hour}
+	service, _ := NewJWTAuthService[JWTClaims](options, s.store)
+	_, auth, _ := service.GetTokenPair(userClaims("user-1"), Client{UserAgent: "Safari", IP: "10.0.0.2"})
+	claims, _ := service.GetClaim(auth)
+
+	sessions, _ := s.store.ListSessions("user-1")
+
+	s.Require().Len(sessions, 1)
+	s.Equal(claims.Family, sessions[0].Family)
+	s.Equal("Safari", sessions[0].UserAgent)
+	s.Equal("10.0.0.2", sessions[0].IP)
+}
+
+func TestGormRefreshStore(t *testing.T) {
+	suite.Run(t, new(gormRefreshStoreTestSuite))
+}
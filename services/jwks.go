@@ -0,0 +1,307 @@
+package services
+
+import (
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rsa"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"math/big"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/dino16m/golearn-core/config"
+	"github.com/dino16m/golearn-core/errors"
+	"github.com/golang-jwt/jwt"
+)
+
+// JWK is a public key encoded as a JSON Web Key (RFC 7517)
+type JWK struct {
+	Kty string `json:"kty"`
+	Kid string `json:"kid,omitempty"`
+	Use string `json:"use,omitempty"`
+	Alg string `json:"alg,omitempty"`
+	N   string `json:"n,omitempty"`
+	E   string `json:"e,omitempty"`
+	Crv string `json:"crv,omitempty"`
+	X   string `json:"x,omitempty"`
+	Y   string `json:"y,omitempty"`
+}
+
+// JWKS is a JSON Web Key Set as served from /.well-known/jwks.json
+type JWKS struct {
+	Keys []JWK `json:"keys"`
+}
+
+// JWKS returns the public keys of the key set which are still accepted,
+// HMAC keys are secret and never published
+func (ks *KeySet) JWKS() JWKS {
+	ks.mu.RLock()
+	defer ks.mu.RUnlock()
+	jwks := JWKS{Keys: []JWK{}}
+	for _, id := range ks.order {
+		key := ks.keys[id]
+		if key.retired(ks.now()) {
+			continue
+		}
+		jwk, ok := encodeJWK(key.verifyKey)
+		if !ok {
+			continue
+		}
+		jwk.Kid = id
+		jwk.Use = "sig"
+		jwk.Alg = key.method.Alg()
+		jwks.Keys = append(jwks.Keys, jwk)
+	}
+	return jwks
+}
+
+// JWKS returns the public keys tokens issued by the service can be verified with
+func (a JWTAuthService[C]) JWKS() JWKS {
+	return a.keys.JWKS()
+}
+
+func encodeJWK(key interface{}) (JWK, bool) {
+	b64 := base64.RawURLEncoding.EncodeToString
+	switch key := key.(type) {
+	case *rsa.PublicKey:
+		return JWK{
+			Kty: "RSA",
+			N:   b64(key.N.Bytes()),
+			E:   b64(big.NewInt(int64(key.E)).Bytes()),
+		}, true
+	case *ecdsa.PublicKey:
+		size := (key.Curve.Params().BitSize + 7) / 8
+		return JWK{
+			Kty: "EC",
+			Crv: key.Curve.Params().Name,
+			X:   b64(key.X.FillBytes(make([]byte, size))),
+			Y:   b64(key.Y.FillBytes(make([]byte, size))),
+		}, true
+	case ed25519.PublicKey:
+		return JWK{Kty: "OKP", Crv: "Ed25519", X: b64(key)}, true
+	}
+	return JWK{}, false
+}
+
+// PublicKey decodes the public key held by the JWK
+func (k JWK) PublicKey() (interface{}, error) {
+	decode := base64.RawURLEncoding.DecodeString
+	switch k.Kty {
+	case "RSA":
+		n, err := decode(k.N)
+		if err != nil {
+			return nil, err
+		}
+		e, err := decode(k.E)
+		if err != nil {
+			return nil, err
+		}
+		return &rsa.PublicKey{N: new(big.Int).SetBytes(n), E: int(new(big.Int).SetBytes(e).Int64())}, nil
+	case "EC":
+		var curve elliptic.Curve
+		switch k.Crv {
+		case "P-256":
+			curve = elliptic.P256()
+		case "P-384":
+			curve = elliptic.P384()
+		case "P-521":
+			curve = elliptic.P521()
+		default:
+			return nil, fmt.Errorf("unsupported curve %q", k.Crv)
+		}
+		x, err := decode(k.X)
+		if err != nil {
+			return nil, err
+		}
+		y, err := decode(k.Y)
+		if err != nil {
+			return nil, err
+		}
+		return &ecdsa.PublicKey{Curve: curve, X: new(big.Int).SetBytes(x), Y: new(big.Int).SetBytes(y)}, nil
+	case "OKP":
+		if k.Crv != "Ed25519" {
+			return nil, fmt.Errorf("unsupported curve %q", k.Crv)
+		}
+		x, err := decode(k.X)
+		if err != nil {
+			return nil, err
+		}
+		if len(x) != ed25519.PublicKeySize {
+			return nil, fmt.Errorf("invalid Ed25519 key size")
+		}
+		return ed25519.PublicKey(x), nil
+	}
+	return nil, fmt.Errorf("unsupported key type %q", k.Kty)
+}
+
+// accepts reports whether a token signed with alg can be verified with the
+// key, keys which do not name their algorithm accept the algorithms of
+// their key type
+func (k JWK) accepts(alg string) bool {
+	if k.Alg != "" {
+		return k.Alg == alg
+	}
+	switch k.Kty {
+	case "RSA":
+		return strings.HasPrefix(alg, "RS") || strings.HasPrefix(alg, "PS")
+	case "EC":
+		return strings.HasPrefix(alg, "ES")
+	case "OKP":
+		return alg == "EdDSA"
+	}
+	return false
+}
+
+type remoteKey struct {
+	jwk JWK
+	key interface{}
+}
+
+const remoteJWKSRefreshCooldown = 30 * time.Second
+
+// RemoteJWKS fetches and caches the key set another service publishes.
+// The cache is refreshed once it is older than its ttl, or when a token
+// names an unknown kid, at most once per cooldown
+type RemoteJWKS struct {
+	url       string
+	ttl       time.Duration
+	cooldown  time.Duration
+	client    *http.Client
+	keys      map[string]remoteKey
+	fetchedAt time.Time
+	now       func() time.Time
+	mu        sync.RWMutex
+	fetchMu   sync.Mutex
+}
+
+// NewRemoteJWKS constructs a RemoteJWKS for the key set served at url
+func NewRemoteJWKS(url string, ttl time.Duration) *RemoteJWKS {
+	return &RemoteJWKS{
+		url:      url,
+		ttl:      ttl,
+		cooldown: remoteJWKSRefreshCooldown,
+		client:   &http.Client{Timeout: 10 * time.Second},
+		keys:     make(map[string]remoteKey),
+		now:      time.Now,
+	}
+}
+
+// Key returns the public key with the given kid which verifies tokens
+// signed with alg
+func (r *RemoteJWKS) Key(kid string, alg string) (interface{}, error) {
+	key, ok, fetchedAt := r.cached(kid)
+	now := r.now()
+	stale := fetchedAt.IsZero() || now.Sub(fetchedAt) >= r.ttl
+	switch {
+	case ok && stale:
+		// a cached key is served as is while another caller refreshes the set
+		if r.fetchMu.TryLock() {
+			r.refresh(fetchedAt)
+			r.fetchMu.Unlock()
+			key, ok, _ = r.cached(kid)
+		}
+	case !ok && (stale || now.Sub(fetchedAt) >= r.cooldown):
+		r.fetchMu.Lock()
+		err := r.refresh(fetchedAt)
+		r.fetchMu.Unlock()
+		key, ok, _ = r.cached(kid)
+		if err != nil && !ok {
+			return nil, err
+		}
+	}
+	if !ok {
+		return nil, fmt.Errorf("unknown signing key %q", kid)
+	}
+	if !key.jwk.accepts(alg) {
+		return nil, fmt.Errorf("unexpected signing method: %v", alg)
+	}
+	return key.key, nil
+}
+
+func (r *RemoteJWKS) cached(kid string) (remoteKey, bool, time.Time) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	key, ok := r.keys[kid]
+	return key, ok, r.fetchedAt
+}
+
+// refresh replaces the cached keys unless they were fetched again after
+// seen, the cache is kept when fetching fails. Callers hold fetchMu
+func (r *RemoteJWKS) refresh(seen time.Time) error {
+	r.mu.RLock()
+	fetched := !r.fetchedAt.Equal(seen)
+	r.mu.RUnlock()
+	if fetched {
+		return nil
+	}
+	now := r.now()
+	keys, err := r.fetch()
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.fetchedAt = now
+	if err != nil {
+		return err
+	}
+	r.keys = keys
+	return nil
+}
+
+func (r *RemoteJWKS) fetch() (map[string]remoteKey, error) {
+	res, err := r.client.Get(r.url)
+	if err != nil {
+		return nil, err
+	}
+	defer res.Body.Close()
+	if res.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("fetching %s responded with status %d", r.url, res.StatusCode)
+	}
+	var jwks JWKS
+	if err := json.NewDecoder(res.Body).Decode(&jwks); err != nil {
+		return nil, err
+	}
+	keys := make(map[string]remoteKey)
+	for _, jwk := range jwks.Keys {
+		if jwk.Use != "" && jwk.Use != "sig" {
+			continue
+		}
+		key, err := jwk.PublicKey()
+		if err != nil {
+			continue
+		}
+		keys[jwk.Kid] = remoteKey{jwk: jwk, key: key}
+	}
+	return keys, nil
+}
+
+// RemoteJWTVerifier verifies tokens with the keys another service publishes
+// as a JWKS, it lets resource servers check tokens without any secret
+type RemoteJWTVerifier[C any] struct {
+	jwks       *RemoteJWKS
+	validation claimsValidation
+}
+
+// NewRemoteJWTVerifier constructs a RemoteJWTVerifier for the key set
+// served at jwksURL, caching the keys for cacheTTL. Tokens are checked
+// against the Issuer, Audience and Leeway of options
+func NewRemoteJWTVerifier[C any](jwksURL string, cacheTTL time.Duration, options config.JwtOptions) RemoteJWTVerifier[C] {
+	return RemoteJWTVerifier[C]{
+		jwks:       NewRemoteJWKS(jwksURL, cacheTTL),
+		validation: newClaimsValidation(options),
+	}
+}
+
+func (v RemoteJWTVerifier[C]) GetClaim(tokenStr string) (Claims[C], errors.ApplicationError) {
+	return parseClaims[C](tokenStr, func(t *jwt.Token) (interface{}, error) {
+		kid, _ := t.Header["kid"].(string)
+		key, err := v.jwks.Key(kid, t.Method.Alg())
+		if err != nil {
+			return nil, errors.UnauthorizedError(err.Error())
+		}
+		return key, nil
+	}, v.validation)
+}
@@ -0,0 +1,188 @@
+package services
+
+import (
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/dino16m/golearn-core/config"
+	"github.com/dino16m/golearn-core/errors"
+	"github.com/stretchr/testify/suite"
+)
+
+type jwksTestSuite struct {
+	suite.Suite
+	issuer  JWTAuthService[JWTClaims]
+	server  *httptest.Server
+	fetches int32
+	stall   int32
+	release chan struct{}
+	now     time.Time
+}
+
+func (s *jwksTestSuite) SetupTest() {
+	key, _ := rsa.GenerateKey(rand.Reader, 2048)
+	issuer, err := NewJWTAuthService[JWTClaims](config.JwtOptions{
+		Timeout:    time.Minute,
+		MaxRefresh: time.Hour,
+		Keys: []config.JwtKey{
+			{ID: "rsa-1", Algorithm: "RS256", PrivateKey: encodePrivateKey(key)},
+			{ID: "hmac", Key: "secret"},
+		},
+		CurrentKeyID: "rsa-1",
+	}, NewInMemoryTokenBlacklister())
+	s.Require().NoError(err)
+	s.issuer = issuer
+	s.fetches = 0
+	s.stall = 0
+	s.release = make(chan struct{})
+	s.now = time.Now()
+	s.server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&s.fetches, 1)
+		if atomic.LoadInt32(&s.stall) == 1 {
+			<-s.release
+		}
+		json.NewEncoder(w).Encode(s.issuer.JWKS())
+	}))
+}
+
+func (s *jwksTestSuite) TearDownTest() {
+	s.server.Close()
+}
+
+func (s *jwksTestSuite) verifier() RemoteJWTVerifier[JWTClaims] {
+	verifier := NewRemoteJWTVerifier[JWTClaims](s.server.URL, time.Hour, config.JwtOptions{})
+	verifier.jwks.now = func() time.Time { return s.now }
+	return verifier
+}
+
+func (s *jwksTestSuite) TestJWKSPublishesOnlyPublicKeys() {
+	jwks := s.issuer.JWKS()
+
+	s.Require().Len(jwks.Keys, 1)
+	s.Equal("rsa-1", jwks.Keys[0].Kid)
+	s.Equal("RSA", jwks.Keys[0].Kty)
+	s.Equal("RS256", jwks.Keys[0].Alg)
+	s.Equal("sig", jwks.Keys[0].Use)
+}
+
+func (s *jwksTestSuite) TestJWKRoundTrip() {
+	rsaKey, _ := rsa.GenerateKey(rand.Reader, 2048)
+	ecKey, _ := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	edKey, _, _ := ed25519.GenerateKey(rand.Reader)
+
+	for _, public := range []interface{}{&rsaKey.PublicKey, &ecKey.PublicKey, edKey} {
+		jwk, ok := encodeJWK(public)
+		s.Require().True(ok)
+		decoded, err := jwk.PublicKey()
+		s.Require().NoError(err)
+		s.Equal(public, decoded)
+	}
+}
+
+func (s *jwksTestSuite) TestRemoteVerifierAcceptsIssuedToken() {
+	token := mustSign(s.issuer.GetToken(userClaims("user-1")))
+
+	claims, err := s.verifier().GetClaim(token)
+
+	s.Require().Nil(err)
+	s.Equal("user-1", claims.UserID)
+}
+
+func (s *jwksTestSuite) TestRemoteVerifierCachesKeys() {
+	verifier := s.verifier()
+	token := mustSign(s.issuer.GetToken(userClaims("user-1")))
+
+	verifier.GetClaim(token)
+	verifier.GetClaim(token)
+
+	s.Equal(int32(1), atomic.LoadInt32(&s.fetches))
+}
+
+func (s *jwksTestSuite) TestRemoteVerifierRefreshesOnUnknownKid() {
+	verifier := s.verifier()
+	verifier.GetClaim(mustSign(s.issuer.GetToken(userClaims("user-1"))))
+	key, _ := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	s.Require().NoError(s.issuer.RotateKey(config.JwtKey{
+		ID: "ec-1", Algorithm: "ES256", PrivateKey: encodePrivateKey(key)}))
+	s.now = s.now.Add(remoteJWKSRefreshCooldown)
+
+	claims, err := verifier.GetClaim(mustSign(s.issuer.GetToken(userClaims("user-2"))))
+
+	s.Require().Nil(err)
+	s.Equal("user-2", claims.UserID)
+	s.Equal(int32(2), atomic.LoadInt32(&s.fetches))
+}
+
+func (s *jwksTestSuite) TestRemoteVerifierThrottlesRefresh() {
+	verifier := s.verifier()
+	verifier.GetClaim(mustSign(s.issuer.GetToken(userClaims("user-1"))))
+	key, _ := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	s.Require().NoError(s.issuer.RotateKey(config.JwtKey{
+		ID: "ec-1", Algorithm: "ES256", PrivateKey: encodePrivateKey(key)}))
+	token := mustSign(s.issuer.GetToken(userClaims("user-2")))
+
+	_, err := verifier.GetClaim(token)
+	verifier.GetClaim(token)
+
+	s.NotNil(err)
+	s.Equal(int32(1), atomic.LoadInt32(&s.fetches))
+}
+
+func (s *jwksTestSuite) TestRemoteVerifierServesCachedKeysDuringFetch() {
+	verifier := s.verifier()
+	cached := mustSign(s.issuer.GetToken(userClaims("user-1")))
+	verifier.GetClaim(cached)
+	key, _ := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	s.Require().NoError(s.issuer.RotateKey(config.JwtKey{
+		ID: "ec-1", Algorithm: "ES256", PrivateKey: encodePrivateKey(key)}))
+	s.now = s.now.Add(remoteJWKSRefreshCooldown)
+	atomic.StoreInt32(&s.stall, 1)
+	rotated := make(chan errors.ApplicationError)
+	go func() {
+		_, err := verifier.GetClaim(mustSign(s.issuer.GetToken(userClaims("user-2"))))
+		rotated <- err
+	}()
+	s.Eventually(func() bool { return atomic.LoadInt32(&s.fetches) == 2 }, time.Second, time.Millisecond)
+
+	claims, err := verifier.GetClaim(cached)
+
+	s.Require().Nil(err)
+	s.Equal("user-1", claims.UserID)
+	close(s.release)
+	s.Nil(<-rotated)
+}
+
+func (s *jwksTestSuite) TestRemoteVerifierRejectsHMACToken() {
+	hmac, _ := NewJWTAuthService[JWTClaims](config.JwtOptions{Key: "secret", Timeout: time.Minute}, NewInMemoryTokenBlacklister())
+
+	_, err := s.verifier().GetClaim(mustSign(hmac.GetToken(userClaims("user-1"))))
+
+	s.NotNil(err)
+}
+
+func (s *jwksTestSuite) TestRemoteVerifierRejectsAlgorithmMismatch() {
+	verifier := s.verifier()
+	verifier.GetClaim(mustSign(s.issuer.GetToken(userClaims("user-1"))))
+	key, _ := rsa.GenerateKey(rand.Reader, 2048)
+	forger, _ := NewJWTAuthService[JWTClaims](config.JwtOptions{
+		Timeout: time.Minute,
+		Keys:    []config.JwtKey{{ID: "rsa-1", Algorithm: "RS384", PrivateKey: encodePrivateKey(key)}},
+	}, NewInMemoryTokenBlacklister())
+
+	_, err := verifier.GetClaim(mustSign(forger.GetToken(userClaims("user-1"))))
+
+	s.NotNil(err)
+}
+
+func TestJWKS(t *testing.T) {
+	suite.Run(t, new(jwksTestSuite))
+}
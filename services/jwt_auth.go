@@ -11,121 +11,553 @@ import (
 	"github.com/google/uuid"
 )
 
-type JWTClaims = map[string]interface{}
-
+// RefreshValidator tracks the current refresh token of every family, only
+// the latest token issued in a family is valid. expiresAt is when the
+// token, or every token of the family, has expired so the entry can be dropped
 type RefreshValidator interface {
-	BlackListFamily(family string)
-	InvalidateJTI(jti string)
-	ValidateJTI(family string, jti string) bool
+	BlackListFamily(family string, expiresAt time.Time) error
+	// SetCurrentJTI makes jti the only valid token of the family
+	SetCurrentJTI(family string, jti string, expiresAt time.Time) error
+	// ValidateJTI reports whether jti is the current token of a family
+	// which is not blacklisted, tokens of unknown families are invalid
+	ValidateJTI(family string, jti string) (bool, error)
+	// RotateJTI atomically replaces oldJTI with newJTI as the current token
+	// of the family, it reports false without changing anything when oldJTI
+	// is not valid. pair is the token pair issued for the rotation, it is
+	// kept with the rotation until graceUntil for Replay
+	RotateJTI(family string, oldJTI string, newJTI string, expiresAt time.Time, pair TokenPair, graceUntil time.Time) (bool, error)
+	// Replay returns the pair issued when jti was last rotated if it is
+	// still within its grace period and the family is not blacklisted
+	Replay(family string, jti string) (TokenPair, bool, error)
+}
+
+// TokenVerifier verifies a token and returns its claims
+type TokenVerifier[C any] interface {
+	GetClaim(tokenStr string) (Claims[C], errors.ApplicationError)
+}
+
+// ClaimsProvider builds the custom claims of the tokens issued to a user,
+// e.g. their roles or tenant. It is consulted whenever tokens are issued
+// or refreshed so the claims never go stale. An ApplicationError it
+// returns, e.g. for a user who was removed, is returned to the client
+type ClaimsProvider[C any] interface {
+	Claims(userID interface{}) (C, error)
 }
 
-type JWTAuthService struct {
+// JWTAuthService issues and verifies tokens whose custom claims are C
+type JWTAuthService[C any] struct {
 	options          config.JwtOptions
 	refreshValidator RefreshValidator
+	keys             *KeySet
+	validation       claimsValidation
+	claimsProvider   ClaimsProvider[C]
 }
 
+// ErrSessionExpired is returned by RefreshToken once the family outlived
+// the MaxSessionLifetime, ErrSessionIdle once it was not refreshed for the
+// SessionIdleTimeout. The user must authenticate again in both cases, the
+// error codes "session_expired" and "session_idle" tell them apart
+var (
+	ErrSessionExpired = errors.WithCode(
+		errors.UnauthorizedError("Session expired, please log in again"), "session_expired")
+	ErrSessionIdle = errors.WithCode(
+		errors.UnauthorizedError("Session timed out due to inactivity, please log in again"), "session_idle")
+)
+
 type TokenPair struct {
 	Refresh string `json:"refreshToken"`
 	Auth    string `json:"authToken"`
 }
 
-func NewJWTAuthService(options config.JwtOptions, refreshValidator RefreshValidator) JWTAuthService {
-	return JWTAuthService{options: options, refreshValidator: refreshValidator}
+// NewJWTAuthService constructs a JWTAuthService signing with the algorithm
+// and keys of options, it fails if the keys cannot be loaded
+func NewJWTAuthService[C any](options config.JwtOptions, refreshValidator RefreshValidator) (JWTAuthService[C], error) {
+	keys, err := newKeySetFromOptions(options)
+	if err != nil {
+		return JWTAuthService[C]{}, fmt.Errorf("jwt: %w", err)
+	}
+	return JWTAuthService[C]{
+		options:          options,
+		refreshValidator: refreshValidator,
+		keys:             keys,
+		validation:       newClaimsValidation(options),
+	}, nil
+}
+
+// NewJWTAuthServiceWithClaimsProvider constructs a JWTAuthService which
+// builds custom claims with provider instead of copying them from the
+// claims it is given or the refreshed token
+func NewJWTAuthServiceWithClaimsProvider[C any](options config.JwtOptions, refreshValidator RefreshValidator, provider ClaimsProvider[C]) (JWTAuthService[C], error) {
+	service, err := NewJWTAuthService[C](options, refreshValidator)
+	if err != nil {
+		return JWTAuthService[C]{}, err
+	}
+	service.claimsProvider = provider
+	return service, nil
+}
+
+// customClaims returns the custom claims of the tokens issued to userID,
+// current when there is no claims provider
+func (a JWTAuthService[C]) customClaims(userID interface{}, current C) (C, errors.ApplicationError) {
+	if a.claimsProvider == nil {
+		return current, nil
+	}
+	custom, err := a.claimsProvider.Claims(userID)
+	if err != nil {
+		if appErr, ok := err.(errors.ApplicationError); ok {
+			return custom, appErr
+		}
+		return custom, errors.InternalServerError("Could not issue tokens")
+	}
+	return custom, nil
+}
+
+// Keys returns the key set tokens are signed and verified with,
+// it is shared by every copy of the service
+func (a JWTAuthService[C]) Keys() *KeySet {
+	return a.keys
 }
 
-func (a JWTAuthService) RefreshToken(refreshToken string) (TokenPair, errors.ApplicationError) {
-	claim, err := a.GetClaim(refreshToken)
+// RotateKey makes key sign new tokens, the previous key keeps verifying
+// tokens until the longest lived token it could have signed has expired
+func (a JWTAuthService[C]) RotateKey(key config.JwtKey) error {
+	a.keys.Prune()
+	return a.keys.Rotate(key, a.maxTokenLifetime())
+}
+
+// maxTokenLifetime is how long the longest lived token the service issues
+// stays valid
+func (a JWTAuthService[C]) maxTokenLifetime() time.Duration {
+	if a.options.MaxRefresh > a.options.Timeout {
+		return a.options.MaxRefresh
+	}
+	return a.options.Timeout
+}
+
+// RefreshToken exchanges a refresh token for a new token pair of the same
+// family, client describes who is refreshing. The token is rotated
+// atomically so only one of concurrent refreshes with the same token
+// rotates it, the others get the same pair during the RefreshGracePeriod
+func (a JWTAuthService[C]) RefreshToken(refreshToken string, client Client) (TokenPair, errors.ApplicationError) {
+	claims, err := a.GetClaim(refreshToken)
 
 	if err != nil {
 		return TokenPair{}, err
 	}
 
-	if claim["use"] != types.RefreshTokenKey {
+	if claims.Use != types.RefreshTokenKey {
 		return TokenPair{}, errors.UnauthorizedError("This is not a refresh token")
 	}
 
-	jti := claim["jti"].(string)
+	if claims.ID == "" || claims.Family == "" {
+		return TokenPair{}, errors.UnauthorizedError("Malformed refresh token")
+	}
+
+	revoked, storeErr := a.Revoked(claims.RegisteredClaims)
+	if storeErr != nil {
+		return TokenPair{}, errors.InternalServerError("Could not check token revocation")
+	}
+	if revoked {
+		return TokenPair{}, errors.UnauthorizedError("Token has been revoked")
+	}
+
+	authTime, err := a.checkSessionAge(claims.RegisteredClaims)
+	if err != nil {
+		return TokenPair{}, err
+	}
 
-	family := claim["fam"].(string)
+	custom, err := a.customClaims(claims.UserID, claims.Custom)
+	if err != nil {
+		return TokenPair{}, err
+	}
+	freshClaims := Claims[C]{
+		RegisteredClaims: RegisteredClaims{UserID: claims.UserID, Family: claims.Family, AuthTime: authTime},
+		Custom:           custom,
+	}
+	refresh, issued, signErr := a.signRefreshToken(freshClaims)
+	if signErr != nil {
+		return TokenPair{}, errors.InternalServerError("Could not issue tokens")
+	}
+	auth, signErr := a.signAuthToken(freshClaims)
+	if signErr != nil {
+		return TokenPair{}, errors.InternalServerError("Could not issue tokens")
+	}
+	pair := TokenPair{Refresh: refresh, Auth: auth}
 
-	valid := a.refreshValidator.ValidateJTI(family, jti)
-	if !valid {
-		a.refreshValidator.BlackListFamily(family)
+	var graceUntil time.Time
+	if a.options.RefreshGracePeriod > 0 {
+		graceUntil = time.Now().Add(a.options.RefreshGracePeriod)
+	}
+	rotated, storeErr := a.refreshValidator.RotateJTI(claims.Family, claims.ID, issued.ID, time.Unix(issued.ExpiresAt, 0), pair, graceUntil)
+	if storeErr != nil {
+		return TokenPair{}, errors.InternalServerError("Could not rotate refresh token")
+	}
+	if !rotated && a.options.RefreshGracePeriod > 0 {
+		replayed, ok, storeErr := a.refreshValidator.Replay(claims.Family, claims.ID)
+		if storeErr != nil {
+			return TokenPair{}, errors.InternalServerError("Could not rotate refresh token")
+		}
+		if ok {
+			return replayed, nil
+		}
+	}
+	if !rotated {
+		// the newest token of the family expires at most MaxRefresh from now
+		familyExpiry := time.Now().Add(a.options.MaxRefresh)
+		if storeErr := a.refreshValidator.BlackListFamily(claims.Family, familyExpiry); storeErr != nil {
+			return TokenPair{}, errors.InternalServerError("Could not revoke refresh token family")
+		}
 		return TokenPair{}, errors.UnauthorizedError("Blacklisted token used")
 	}
+	if store, ok := a.refreshValidator.(SessionStore); ok {
+		if storeErr := store.SaveSession(sessionOf(issued, client), issued.ID, time.Unix(issued.ExpiresAt, 0)); storeErr != nil {
+			return TokenPair{}, errors.InternalServerError("Could not save session")
+		}
+	}
 
-	a.refreshValidator.InvalidateJTI(jti)
+	return pair, nil
+}
 
-	freshClaim := JWTClaims{
-		config.UserIdClaim: claim[config.UserIdClaim],
+// checkSessionAge enforces the MaxSessionLifetime and SessionIdleTimeout
+// on the family of a refresh token and returns when the family was
+// authenticated. The lifetime of tokens issued without auth_time is counted
+// from the creation of their family when the refresh validator is a
+// SessionStore, they are rejected otherwise
+func (a JWTAuthService[C]) checkSessionAge(claims RegisteredClaims) (int64, errors.ApplicationError) {
+	now := a.validation.now()
+	authTime := claims.AuthTime
+	lifetime := a.options.MaxSessionLifetime
+	if lifetime > 0 && authTime == 0 {
+		created, err := a.familyCreatedAt(claims)
+		if err != nil {
+			return 0, err
+		}
+		authTime = created
+	}
+	if lifetime > 0 && now.Sub(time.Unix(authTime, 0)) > lifetime {
+		return 0, ErrSessionExpired
+	}
+	idle := a.options.SessionIdleTimeout
+	if idle > 0 && now.Sub(time.Unix(claims.IssuedAt, 0)) > idle {
+		return 0, ErrSessionIdle
 	}
+	return authTime, nil
+}
 
-	refresh := a.GetRefreshToken(jwt.MapClaims{"uid": freshClaim[config.UserIdClaim], "fam": family})
-	auth := a.GetToken(claim)
+// familyCreatedAt returns when the session of the family of claims was
+// started, it fails when the refresh validator does not record sessions
+func (a JWTAuthService[C]) familyCreatedAt(claims RegisteredClaims) (int64, errors.ApplicationError) {
+	store, ok := a.refreshValidator.(SessionStore)
+	if !ok {
+		return 0, ErrSessionExpired
+	}
+	sessions, err := store.ListSessions(claims.Subject)
+	if err != nil {
+		return 0, errors.InternalServerError("Could not load session")
+	}
+	for _, session := range sessions {
+		if session.Family == claims.Family {
+			return session.CreatedAt.Unix(), nil
+		}
+	}
+	return 0, ErrSessionExpired
+}
 
-	return TokenPair{
-		Refresh: refresh,
-		Auth:    auth,
-	}, nil
+// GetRefreshToken issues a refresh token in the family of claims and
+// makes it the current token of the family, a new family authenticated now
+// is started when claims has none
+func (a JWTAuthService[C]) GetRefreshToken(claims Claims[C]) (string, error) {
+	if claims.Family == "" && claims.AuthTime == 0 {
+		claims.AuthTime = time.Now().Unix()
+	}
+	custom, appErr := a.customClaims(claims.UserID, claims.Custom)
+	if appErr != nil {
+		return "", appErr
+	}
+	claims.Custom = custom
+	token, issued, err := a.signRefreshToken(claims)
+	if err != nil {
+		return "", err
+	}
+	if err := a.recordRefreshToken(issued, Client{}); err != nil {
+		return "", err
+	}
+	return token, nil
+}
+
+// signRefreshToken signs a refresh token without recording it
+func (a JWTAuthService[C]) signRefreshToken(claims Claims[C]) (string, RegisteredClaims, error) {
+	now := time.Now()
+	claims.IssuedAt = now.Unix()
+	claims.IssuedAtNanos = int64(now.Nanosecond())
+	claims.NotBefore = now.Unix()
+	claims.ExpiresAt = now.Add(a.options.MaxRefresh).Unix()
+	if lifetime := a.options.MaxSessionLifetime; lifetime > 0 && claims.AuthTime != 0 {
+		// the token is not outlived by the session of its family
+		if end := time.Unix(claims.AuthTime, 0).Add(lifetime).Unix(); end < claims.ExpiresAt {
+			claims.ExpiresAt = end
+		}
+	}
+	claims.Use = types.RefreshTokenKey
+	claims.ID = getJTI()
+
+	if claims.Family == "" {
+		claims.Family = getJTI()
+	}
+	a.stampRegisteredClaims(&claims.RegisteredClaims)
+
+	token, err := a.sign(claims)
+	if err != nil {
+		return "", RegisteredClaims{}, err
+	}
+	return token, claims.RegisteredClaims, nil
 }
 
-func (a JWTAuthService) GetRefreshToken(baseClaims JWTClaims) string {
-	baseClaims["iat"] = time.Now().Unix()
-	baseClaims["nbf"] = time.Now().Unix()
-	baseClaims["exp"] = time.Now().Add(a.options.MaxRefresh).Unix()
-	baseClaims["use"] = types.RefreshTokenKey
-	baseClaims["jti"] = getJTI()
+// GetToken issues an auth token for claims
+func (a JWTAuthService[C]) GetToken(claims Claims[C]) (string, error) {
+	custom, err := a.customClaims(claims.UserID, claims.Custom)
+	if err != nil {
+		return "", err
+	}
+	claims.Custom = custom
+	return a.signAuthToken(claims)
+}
+
+// signAuthToken signs an auth token for claims as they are
+func (a JWTAuthService[C]) signAuthToken(claims Claims[C]) (string, error) {
+	now := time.Now()
+	claims.ID = getJTI()
+	claims.ExpiresAt = now.Add(a.options.Timeout).Unix()
+	claims.IssuedAt = now.Unix()
+	claims.IssuedAtNanos = int64(now.Nanosecond())
+	claims.NotBefore = now.Unix()
+	claims.Use = types.AuthTokenKey
+	a.stampRegisteredClaims(&claims.RegisteredClaims)
+
+	return a.sign(claims)
+}
 
-	if baseClaims["fam"] == nil || baseClaims["fam"] == "" {
-		baseClaims["fam"] = getJTI()
+// GetTokenPair starts a new refresh token family for client and issues
+// its first token pair. The auth token carries the family so the session
+// it belongs to is known. The family is authenticated at claims.AuthTime,
+// now when it is not set
+func (a JWTAuthService[C]) GetTokenPair(claims Claims[C], client Client) (refreshToken string, authToken string, err error) {
+	custom, appErr := a.customClaims(claims.UserID, claims.Custom)
+	if appErr != nil {
+		return "", "", appErr
+	}
+	claims.Custom = custom
+	if claims.AuthTime == 0 {
+		claims.AuthTime = time.Now().Unix()
+	}
+	refreshToken, issued, err := a.signRefreshToken(Claims[C]{
+		RegisteredClaims: RegisteredClaims{UserID: claims.UserID, AuthTime: claims.AuthTime},
+		Custom:           claims.Custom,
+	})
+	if err != nil {
+		return "", "", err
+	}
+	claims.Family = issued.Family
+	authToken, err = a.signAuthToken(claims)
+	if err != nil {
+		return "", "", err
 	}
+	if err := a.recordRefreshToken(issued, client); err != nil {
+		return "", "", err
+	}
+	return refreshToken, authToken, nil
+}
 
-	token := jwt.NewWithClaims(jwt.SigningMethodHS256, jwt.MapClaims(baseClaims))
+// recordRefreshToken makes an issued refresh token the current token of
+// its family, a SessionStore also records the session it belongs to
+func (a JWTAuthService[C]) recordRefreshToken(issued RegisteredClaims, client Client) error {
+	expiresAt := time.Unix(issued.ExpiresAt, 0)
+	store, ok := a.refreshValidator.(SessionStore)
+	if !ok {
+		return a.refreshValidator.SetCurrentJTI(issued.Family, issued.ID, expiresAt)
+	}
+	return store.SaveSession(sessionOf(issued, client), issued.ID, expiresAt)
+}
 
-	tokenString, _ := token.SignedString([]byte(a.options.Key))
-	return tokenString
+// Revoked reports whether the token of claims has been revoked, tokens are
+// never revoked when the refresh validator is not a TokenRevoker
+func (a JWTAuthService[C]) Revoked(claims RegisteredClaims) (bool, error) {
+	revoker, ok := a.refreshValidator.(TokenRevoker)
+	if !ok {
+		return false, nil
+	}
+	return revoker.IsRevoked(claims.ID, claims.Subject, claims.IssuedAtTime())
 }
 
-func (a JWTAuthService) GetToken(claim JWTClaims) string {
-	claim["exp"] = time.Now().Add(a.options.Timeout).Unix()
-	claim["iat"] = time.Now().Unix()
-	claim["nbf"] = time.Now().Unix()
-	claim["use"] = types.AuthTokenKey
+// Active reports whether the token of claims can still be used, it must
+// not be revoked and a refresh token must be the current token of a family
+// within its session limits
+func (a JWTAuthService[C]) Active(claims RegisteredClaims) (bool, error) {
+	revoked, err := a.Revoked(claims)
+	if err != nil || revoked {
+		return false, err
+	}
+	if claims.Use != types.RefreshTokenKey {
+		return true, nil
+	}
+	if _, err := a.checkSessionAge(claims); err != nil {
+		return false, nil
+	}
+	return a.refreshValidator.ValidateJTI(claims.Family, claims.ID)
+}
 
-	token := jwt.NewWithClaims(jwt.SigningMethodHS256, jwt.MapClaims(claim))
+// Logout ends the session the auth token of claims belongs to, its refresh
+// token family is revoked and so is the auth token when the refresh
+// validator is a TokenRevoker
+func (a JWTAuthService[C]) Logout(claims Claims[C]) errors.ApplicationError {
+	if claims.Family != "" {
+		familyExpiry := time.Now().Add(a.options.MaxRefresh)
+		if err := a.refreshValidator.BlackListFamily(claims.Family, familyExpiry); err != nil {
+			return errors.InternalServerError("Could not revoke refresh token family")
+		}
+	}
+	revoker, ok := a.refreshValidator.(TokenRevoker)
+	if !ok || claims.ID == "" {
+		return nil
+	}
+	if err := revoker.RevokeToken(claims.ID, time.Unix(claims.ExpiresAt, 0)); err != nil {
+		return errors.InternalServerError("Could not revoke token")
+	}
+	return nil
+}
 
-	tokenString, _ := token.SignedString([]byte(a.options.Key))
-	return tokenString
+// LogoutAll revokes every token issued to the user of claims so far, it
+// requires the refresh validator to be a TokenRevoker
+func (a JWTAuthService[C]) LogoutAll(claims Claims[C]) errors.ApplicationError {
+	revoker, ok := a.refreshValidator.(TokenRevoker)
+	if !ok {
+		return errors.InternalServerError("Token revocation is not supported")
+	}
+	now := time.Now()
+	if err := revoker.RevokeUserTokens(claims.Subject, now, now.Add(a.maxTokenLifetime())); err != nil {
+		return errors.InternalServerError("Could not revoke tokens")
+	}
+	if store, ok := a.refreshValidator.(SessionStore); ok {
+		if err := store.RevokeAllSessions(claims.Subject); err != nil {
+			return errors.InternalServerError("Could not revoke sessions")
+		}
+	}
+	return nil
 }
 
-func (a JWTAuthService) GetTokenPair(claim JWTClaims) (refreshToken string, authToken string) {
-	refreshToken = a.GetRefreshToken(jwt.MapClaims{"uid": claim[config.UserIdClaim]})
-	authToken = a.GetToken(claim)
-	return refreshToken, authToken
+func sessionOf(issued RegisteredClaims, client Client) Session {
+	return Session{
+		Family:    issued.Family,
+		UserID:    issued.Subject,
+		UserAgent: client.UserAgent,
+		IP:        client.IP,
+	}
 }
 
-func (a JWTAuthService) GetClaim(tokenStr string) (map[string]interface{}, errors.ApplicationError) {
-	token, err := jwt.Parse(tokenStr, func(t *jwt.Token) (interface{}, error) {
-		if _, ok := t.Method.(*jwt.SigningMethodHMAC); !ok {
-			return nil, errors.InternalServerError(fmt.Sprintf("Unexpected signing method: %v", t.Header["alg"]))
+func (a JWTAuthService[C]) GetClaim(tokenStr string) (Claims[C], errors.ApplicationError) {
+	return parseClaims[C](tokenStr, func(t *jwt.Token) (interface{}, error) {
+		kid, _ := t.Header["kid"].(string)
+		key, err := a.keys.verificationKey(kid, t.Method.Alg())
+		if err != nil {
+			return nil, errors.UnauthorizedError(err.Error())
 		}
-		return []byte(a.options.Key), nil
-	})
+		return key.verifyKey, nil
+	}, a.validation)
+}
+
+// parseClaims verifies tokenStr with the key returned by keyFunc and
+// checks its registered claims. Claims which do not decode into Claims[C]
+// are rejected
+func parseClaims[C any](tokenStr string, keyFunc jwt.Keyfunc, validation claimsValidation) (Claims[C], errors.ApplicationError) {
+	parser := jwt.Parser{SkipClaimsValidation: true}
+	claims := Claims[C]{}
+	token, err := parser.ParseWithClaims(tokenStr, &claims, keyFunc)
 	if err != nil {
-		appError, ok := err.(errors.ApplicationError)
-		if ok {
-			return nil, appError
-		} else {
-			return nil, errors.UnauthorizedError(err.Error())
+		if validationErr, ok := err.(*jwt.ValidationError); ok {
+			if appError, ok := validationErr.Inner.(errors.ApplicationError); ok {
+				return Claims[C]{}, appError
+			}
+			if validationErr.Errors&jwt.ValidationErrorMalformed != 0 {
+				return Claims[C]{}, errors.UnauthorizedError("Malformed token")
+			}
 		}
+		return Claims[C]{}, errors.UnauthorizedError(err.Error())
+	}
+
+	if !token.Valid {
+		return Claims[C]{}, errors.UnauthorizedError("Invalid token")
+	}
+	if err := validation.validate(claims.RegisteredClaims); err != nil {
+		return Claims[C]{}, err
+	}
+	return claims, nil
+}
+
+// stampRegisteredClaims sets the iss, aud and sub claims of a token
+func (a JWTAuthService[C]) stampRegisteredClaims(claims *RegisteredClaims) {
+	if a.options.Issuer != "" {
+		claims.Issuer = a.options.Issuer
 	}
+	if len(a.options.Audience) > 0 {
+		claims.Audience = Audience(a.options.Audience)
+	}
+	if claims.UserID != nil {
+		claims.Subject = fmt.Sprint(claims.UserID)
+	}
+}
+
+// claimsValidation checks the registered claims of verified tokens
+type claimsValidation struct {
+	issuer   string
+	audience []string
+	leeway   time.Duration
+	now      func() time.Time
+}
 
-	if claims, ok := token.Claims.(jwt.MapClaims); ok && token.Valid {
-		return claims, nil
-	} else {
-		return nil, errors.UnauthorizedError("Invalid token")
+func newClaimsValidation(options config.JwtOptions) claimsValidation {
+	return claimsValidation{
+		issuer:   options.Issuer,
+		audience: options.Audience,
+		leeway:   options.Leeway,
+		now:      time.Now,
 	}
+}
 
+func (v claimsValidation) validate(claims RegisteredClaims) errors.ApplicationError {
+	now := v.now()
+	if claims.ExpiresAt != 0 && now.Add(-v.leeway).Unix() > claims.ExpiresAt {
+		return errors.UnauthorizedError("Token is expired")
+	}
+	if claims.NotBefore != 0 && now.Add(v.leeway).Unix() < claims.NotBefore {
+		return errors.UnauthorizedError("Token is not valid yet")
+	}
+	if claims.IssuedAt != 0 && now.Add(v.leeway).Unix() < claims.IssuedAt {
+		return errors.UnauthorizedError("Token used before issued")
+	}
+	if v.issuer != "" && claims.Issuer != v.issuer {
+		return errors.UnauthorizedError("Invalid token issuer")
+	}
+	if len(v.audience) > 0 {
+		for _, audience := range v.audience {
+			if claims.Audience.Contains(audience) {
+				return nil
+			}
+		}
+		return errors.UnauthorizedError("Invalid token audience")
+	}
+	return nil
+}
+
+func (a JWTAuthService[C]) sign(claims Claims[C]) (string, error) {
+	key, err := a.keys.signingKey()
+	if err != nil {
+		return "", fmt.Errorf("jwt: %w", err)
+	}
+	token := jwt.NewWithClaims(key.method, claims)
+	if key.id != "" {
+		token.Header["kid"] = key.id
+	}
+	return token.SignedString(key.signKey)
 }
 
 func getJTI() string {
@@ -0,0 +1,593 @@
+package services
+
+import (
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/pem"
+	"fmt"
+	"net/http"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/dino16m/golearn-core/config"
+	"github.com/dino16m/golearn-core/errors"
+	"github.com/dino16m/golearn-core/types"
+	"github.com/golang-jwt/jwt"
+	"github.com/stretchr/testify/suite"
+)
+
+func encodePrivateKey(key interface{}) string {
+	der, err := x509.MarshalPKCS8PrivateKey(key)
+	if err != nil {
+		panic(err)
+	}
+	return string(pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: der}))
+}
+
+func encodePublicKey(key interface{}) string {
+	der, err := x509.MarshalPKIXPublicKey(key)
+	if err != nil {
+		panic(err)
+	}
+	return string(pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: der}))
+}
+
+func mustSign(token string, err error) string {
+	if err != nil {
+		panic(err)
+	}
+	return token
+}
+
+func userClaims(uid interface{}) Claims[JWTClaims] {
+	return Claims[JWTClaims]{RegisteredClaims: RegisteredClaims{UserID: uid}}
+}
+
+func errorCode(err errors.ApplicationError) string {
+	code, _ := errors.ErrorCodeOf(err)
+	return code
+}
+
+type profileClaims struct {
+	Role   string   `json:"role"`
+	Scopes []string `json:"scopes"`
+}
+
+type jwtAuthServiceTestSuite struct {
+	suite.Suite
+	options config.JwtOptions
+}
+
+func (s *jwtAuthServiceTestSuite) SetupTest() {
+	s.options = config.JwtOptions{
+		Key:        "secret",
+		Timeout:    time.Minute,
+		MaxRefresh: time.Hour,
+	}
+}
+
+func (s *jwtAuthServiceTestSuite) service(options config.JwtOptions) JWTAuthService[JWTClaims] {
+	service, err := NewJWTAuthService[JWTClaims](options, NewInMemoryTokenBlacklister())
+	s.Require().NoError(err)
+	return service
+}
+
+func (s *jwtAuthServiceTestSuite) assertPublicKeyVerifies(algorithm string, private interface{}, public interface{}) {
+	issuerOptions := s.options
+	issuerOptions.Algorithm = algorithm
+	issuerOptions.PrivateKey = encodePrivateKey(private)
+	verifierOptions := s.options
+	verifierOptions.Algorithm = algorithm
+	verifierOptions.Key = ""
+	verifierOptions.PublicKey = encodePublicKey(public)
+
+	token := mustSign(s.service(issuerOptions).GetToken(userClaims("user-1")))
+	s.Require().NotEmpty(token)
+	claims, err := s.service(verifierOptions).GetClaim(token)
+
+	s.Require().Nil(err)
+	s.Equal("user-1", claims.UserID)
+}
+
+func (s *jwtAuthServiceTestSuite) TestHS256ByDefault() {
+	service := s.service(s.options)
+
+	claims, err := service.GetClaim(mustSign(service.GetToken(userClaims("user-1"))))
+
+	s.Require().Nil(err)
+	s.Equal("user-1", claims.UserID)
+}
+
+func (s *jwtAuthServiceTestSuite) TestRS256() {
+	key, _ := rsa.GenerateKey(rand.Reader, 2048)
+	s.assertPublicKeyVerifies("RS256", key, &key.PublicKey)
+}
+
+func (s *jwtAuthServiceTestSuite) TestES256() {
+	key, _ := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	s.assertPublicKeyVerifies("ES256", key, &key.PublicKey)
+}
+
+func (s *jwtAuthServiceTestSuite) TestEdDSA() {
+	public, private, _ := ed25519.GenerateKey(rand.Reader)
+	s.assertPublicKeyVerifies("EdDSA", private, public)
+}
+
+func (s *jwtAuthServiceTestSuite) TestRejectsOtherAlgorithms() {
+	hmacService := s.service(s.options)
+	public, private, _ := ed25519.GenerateKey(rand.Reader)
+	options := s.options
+	options.Algorithm = "EdDSA"
+	options.PrivateKey = encodePrivateKey(private)
+	options.PublicKey = encodePublicKey(public)
+
+	_, err := s.service(options).GetClaim(mustSign(hmacService.GetToken(userClaims("user-1"))))
+
+	s.NotNil(err)
+}
+
+func (s *jwtAuthServiceTestSuite) TestPublicKeyOnlyCannotSign() {
+	public, _, _ := ed25519.GenerateKey(rand.Reader)
+	options := s.options
+	options.Algorithm = "EdDSA"
+	options.PublicKey = encodePublicKey(public)
+
+	token, err := s.service(options).GetToken(userClaims("user-1"))
+
+	s.Error(err)
+	s.Empty(token)
+}
+
+func (s *jwtAuthServiceTestSuite) TestInvalidKeyConfiguration() {
+	options := s.options
+	options.Algorithm = "RS256"
+	_, err := NewJWTAuthService[JWTClaims](options, NewInMemoryTokenBlacklister())
+	s.Error(err)
+
+	options.Algorithm = "none"
+	_, err = NewJWTAuthService[JWTClaims](options, NewInMemoryTokenBlacklister())
+	s.Error(err)
+}
+
+func (s *jwtAuthServiceTestSuite) TestKeyRotation() {
+	options := s.options
+	options.Keys = []config.JwtKey{{ID: "2023-01", Key: "first secret"}}
+	service := s.service(options)
+	now := time.Now()
+	service.Keys().now = func() time.Time { return now }
+	oldToken := mustSign(service.GetToken(userClaims("user-1")))
+
+	s.Require().NoError(service.RotateKey(config.JwtKey{ID: "2023-02", Key: "second secret"}))
+	newToken := mustSign(service.GetToken(userClaims("user-1")))
+
+	_, err := service.GetClaim(oldToken)
+	s.Nil(err)
+	_, err = service.GetClaim(newToken)
+	s.Nil(err)
+	s.Equal([]string{"2023-01", "2023-02"}, service.Keys().IDs())
+
+	now = now.Add(options.MaxRefresh)
+	_, err = service.GetClaim(oldToken)
+	s.NotNil(err)
+	_, err = service.GetClaim(newToken)
+	s.Nil(err)
+	service.Keys().Prune()
+	s.Equal([]string{"2023-02"}, service.Keys().IDs())
+}
+
+func (s *jwtAuthServiceTestSuite) TestConcurrentRotationsRetireEveryReplacedKey() {
+	keys := NewKeySet()
+	s.Require().NoError(keys.Add(config.JwtKey{ID: "initial", Key: "secret"}))
+	var wg sync.WaitGroup
+	for i := 0; i < 20; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			s.NoError(keys.Rotate(config.JwtKey{ID: fmt.Sprint("key-", i), Key: "secret"}, 0))
+		}(i)
+	}
+	wg.Wait()
+
+	s.Len(keys.IDs(), 1)
+	current, err := keys.signingKey()
+	s.Require().NoError(err)
+	s.Equal([]string{current.id}, keys.IDs())
+}
+
+func (s *jwtAuthServiceTestSuite) TestCurrentKeyIDSelectsSigningKey() {
+	options := s.options
+	options.Keys = []config.JwtKey{{ID: "a", Key: "first secret"}, {ID: "b", Key: "second secret"}}
+	options.CurrentKeyID = "a"
+	service := s.service(options)
+
+	verifier := options
+	verifier.Keys = []config.JwtKey{{ID: "a", Key: "first secret"}}
+	_, err := s.service(verifier).GetClaim(mustSign(service.GetToken(userClaims("user-1"))))
+
+	s.Nil(err)
+}
+
+func (s *jwtAuthServiceTestSuite) TestUnknownKeyIDRejected() {
+	options := s.options
+	options.Keys = []config.JwtKey{{ID: "a", Key: "secret"}}
+	token := mustSign(s.service(options).GetToken(userClaims("user-1")))
+
+	options.Keys = []config.JwtKey{{ID: "b", Key: "secret"}}
+	_, err := s.service(options).GetClaim(token)
+
+	s.NotNil(err)
+}
+
+func (s *jwtAuthServiceTestSuite) TestRegisteredClaimsStamped() {
+	options := s.options
+	options.Issuer = "https://auth.example.com"
+	options.Audience = []string{"billing", "reports"}
+	service := s.service(options)
+
+	claims, err := service.GetClaim(mustSign(service.GetToken(userClaims(42))))
+
+	s.Require().Nil(err)
+	s.Equal("https://auth.example.com", claims.Issuer)
+	s.Equal(Audience{"billing", "reports"}, claims.Audience)
+	s.Equal("42", claims.Subject)
+}
+
+func (s *jwtAuthServiceTestSuite) TestIssuerMismatchRejected() {
+	options := s.options
+	options.Issuer = "https://auth.example.com"
+	token := mustSign(s.service(options).GetToken(userClaims("user-1")))
+
+	options.Issuer = "https://other.example.com"
+	_, err := s.service(options).GetClaim(token)
+
+	s.NotNil(err)
+}
+
+func (s *jwtAuthServiceTestSuite) TestAudienceMismatchRejected() {
+	options := s.options
+	options.Audience = []string{"billing"}
+	token := mustSign(s.service(options).GetToken(userClaims("user-1")))
+
+	options.Audience = []string{"reports"}
+	_, err := s.service(options).GetClaim(token)
+
+	s.NotNil(err)
+}
+
+func (s *jwtAuthServiceTestSuite) TestAnyConfiguredAudienceAccepted() {
+	options := s.options
+	options.Audience = []string{"billing"}
+	token := mustSign(s.service(options).GetToken(userClaims("user-1")))
+
+	options.Audience = []string{"reports", "billing"}
+	_, err := s.service(options).GetClaim(token)
+
+	s.Nil(err)
+}
+
+func (s *jwtAuthServiceTestSuite) TestLeewayToleratesClockSkew() {
+	options := s.options
+	options.Leeway = 30 * time.Second
+	service := s.service(options)
+	token := mustSign(service.GetToken(userClaims("user-1")))
+
+	service.validation.now = func() time.Time { return time.Now().Add(-20 * time.Second) }
+	_, err := service.GetClaim(token)
+	s.Nil(err)
+
+	service.validation.now = func() time.Time { return time.Now().Add(options.Timeout + 20*time.Second) }
+	_, err = service.GetClaim(token)
+	s.Nil(err)
+
+	service.validation.now = func() time.Time { return time.Now().Add(options.Timeout + time.Minute) }
+	_, err = service.GetClaim(token)
+	s.NotNil(err)
+}
+
+func (s *jwtAuthServiceTestSuite) TestTypedCustomClaims() {
+	service, err := NewJWTAuthService[profileClaims](s.options, NewInMemoryTokenBlacklister())
+	s.Require().NoError(err)
+
+	claims, appErr := service.GetClaim(mustSign(service.GetToken(Claims[profileClaims]{
+		RegisteredClaims: RegisteredClaims{UserID: "user-1"},
+		Custom:           profileClaims{Role: "admin", Scopes: []string{"read", "write"}},
+	})))
+
+	s.Require().Nil(appErr)
+	s.Equal("user-1", claims.UserID)
+	s.Equal(types.AuthTokenKey, claims.Use)
+	s.Equal(profileClaims{Role: "admin", Scopes: []string{"read", "write"}}, claims.Custom)
+}
+
+func (s *jwtAuthServiceTestSuite) TestCustomClaimsSurviveRefresh() {
+	service, _ := NewJWTAuthService[profileClaims](s.options, NewInMemoryTokenBlacklister())
+	refresh, _, _ := service.GetTokenPair(Claims[profileClaims]{
+		RegisteredClaims: RegisteredClaims{UserID: "user-1"},
+		Custom:           profileClaims{Role: "admin"},
+	}, Client{})
+
+	pair, err := service.RefreshToken(refresh, Client{})
+	s.Require().Nil(err)
+	claims, err := service.GetClaim(pair.Auth)
+
+	s.Require().Nil(err)
+	s.Equal("admin", claims.Custom.Role)
+	s.Equal("user-1", claims.UserID)
+}
+
+// roleProvider returns the current role of every user
+type roleProvider struct {
+	roles map[interface{}]string
+}
+
+func (p roleProvider) Claims(userID interface{}) (profileClaims, error) {
+	role, ok := p.roles[userID]
+	if !ok {
+		return profileClaims{}, errors.UnauthorizedError("User not found")
+	}
+	return profileClaims{Role: role}, nil
+}
+
+func (s *jwtAuthServiceTestSuite) TestClaimsProviderConsultedOnIssueAndRefresh() {
+	provider := roleProvider{roles: map[interface{}]string{"user-1": "editor"}}
+	service, _ := NewJWTAuthServiceWithClaimsProvider[profileClaims](s.options, NewInMemoryTokenBlacklister(), provider)
+	refresh, auth, err := service.GetTokenPair(Claims[profileClaims]{
+		RegisteredClaims: RegisteredClaims{UserID: "user-1"},
+		Custom:           profileClaims{Role: "admin"},
+	}, Client{})
+	s.Require().NoError(err)
+	claims, _ := service.GetClaim(auth)
+	s.Equal("editor", claims.Custom.Role)
+
+	provider.roles["user-1"] = "viewer"
+	pair, appErr := service.RefreshToken(refresh, Client{})
+	s.Require().Nil(appErr)
+
+	claims, _ = service.GetClaim(pair.Auth)
+	s.Equal("viewer", claims.Custom.Role)
+	claims, _ = service.GetClaim(pair.Refresh)
+	s.Equal("viewer", claims.Custom.Role)
+}
+
+func (s *jwtAuthServiceTestSuite) TestClaimsProviderErrorStopsRefresh() {
+	provider := roleProvider{roles: map[interface{}]string{"user-1": "editor"}}
+	service, _ := NewJWTAuthServiceWithClaimsProvider[profileClaims](s.options, NewInMemoryTokenBlacklister(), provider)
+	refresh, _, _ := service.GetTokenPair(Claims[profileClaims]{
+		RegisteredClaims: RegisteredClaims{UserID: "user-1"},
+	}, Client{})
+
+	delete(provider.roles, "user-1")
+	_, err := service.RefreshToken(refresh, Client{})
+
+	s.Require().NotNil(err)
+	code, _ := err.Resolve()
+	s.Equal(http.StatusUnauthorized, code)
+}
+
+func (s *jwtAuthServiceTestSuite) TestAuthTimeKeptAcrossRefresh() {
+	service := s.service(s.options)
+	authTime := time.Now().Add(-10 * time.Minute).Unix()
+	claims := userClaims("user-1")
+	claims.AuthTime = authTime
+	refresh, auth, _ := service.GetTokenPair(claims, Client{})
+	issued, _ := service.GetClaim(auth)
+	s.Equal(authTime, issued.AuthTime)
+
+	pair, err := service.RefreshToken(refresh, Client{})
+	s.Require().Nil(err)
+
+	for _, token := range []string{pair.Refresh, pair.Auth} {
+		refreshed, err := service.GetClaim(token)
+		s.Require().Nil(err)
+		s.Equal(authTime, refreshed.AuthTime)
+		s.True(refreshed.AuthenticatedWithin(time.Hour))
+		s.False(refreshed.AuthenticatedWithin(time.Minute))
+	}
+}
+
+func (s *jwtAuthServiceTestSuite) TestNewFamilyAuthenticatedNow() {
+	service := s.service(s.options)
+
+	_, auth, _ := service.GetTokenPair(userClaims("user-1"), Client{})
+	claims, _ := service.GetClaim(auth)
+	refreshClaims, _ := service.GetClaim(mustSign(service.GetRefreshToken(userClaims("user-1"))))
+
+	s.InDelta(time.Now().Unix(), claims.AuthTime, 1)
+	s.InDelta(time.Now().Unix(), refreshClaims.AuthTime, 1)
+}
+
+func (s *jwtAuthServiceTestSuite) TestSessionLifetimeIsAbsolute() {
+	blacklister := NewInMemoryTokenBlacklister()
+	defer blacklister.Close()
+	issuer, _ := NewJWTAuthService[JWTClaims](s.options, blacklister)
+	claims := userClaims("user-1")
+	claims.AuthTime = time.Now().Add(-2 * time.Hour).Unix()
+	refresh, _, _ := issuer.GetTokenPair(claims, Client{})
+	s.options.MaxSessionLifetime = time.Hour
+	service, _ := NewJWTAuthService[JWTClaims](s.options, blacklister)
+
+	_, err := service.RefreshToken(refresh, Client{})
+
+	s.Equal(ErrSessionExpired, err)
+	s.Equal("session_expired", errorCode(err))
+}
+
+func (s *jwtAuthServiceTestSuite) TestRefreshedTokenExpiresWithSession() {
+	s.options.MaxSessionLifetime = time.Hour
+	service := s.service(s.options)
+	claims := userClaims("user-1")
+	claims.AuthTime = time.Now().Add(-50 * time.Minute).Unix()
+	refresh, _, _ := service.GetTokenPair(claims, Client{})
+	pair, err := service.RefreshToken(refresh, Client{})
+	s.Require().Nil(err)
+
+	refreshed, err := service.GetClaim(pair.Refresh)
+
+	s.Require().Nil(err)
+	s.Equal(claims.AuthTime+int64(time.Hour.Seconds()), refreshed.ExpiresAt)
+	service.validation.now = func() time.Time { return time.Now().Add(15 * time.Minute) }
+	_, err = service.RefreshToken(pair.Refresh, Client{})
+	s.NotNil(err)
+}
+
+func (s *jwtAuthServiceTestSuite) TestSessionLifetimeRejectsTokensWithoutAuthTime() {
+	s.options.MaxSessionLifetime = time.Hour
+	service := s.service(s.options)
+	claims := userClaims("user-1")
+	claims.Family = "family"
+	refresh := mustSign(service.GetRefreshToken(claims))
+
+	_, err := service.RefreshToken(refresh, Client{})
+
+	s.Equal(ErrSessionExpired, err)
+	s.Equal("session_expired", errorCode(err))
+}
+
+func (s *jwtAuthServiceTestSuite) TestIdleSessionCannotBeRefreshed() {
+	s.options.SessionIdleTimeout = 30 * time.Minute
+	service := s.service(s.options)
+	refresh, _, _ := service.GetTokenPair(userClaims("user-1"), Client{})
+	service.validation.now = func() time.Time { return time.Now().Add(20 * time.Minute) }
+	pair, err := service.RefreshToken(refresh, Client{})
+	s.Require().Nil(err)
+
+	service.validation.now = func() time.Time { return time.Now().Add(31 * time.Minute) }
+	_, err = service.RefreshToken(pair.Refresh, Client{})
+
+	s.Equal(ErrSessionIdle, err)
+	s.Equal("session_idle", errorCode(err))
+}
+
+func (s *jwtAuthServiceTestSuite) TestSessionLimitsDisabledByDefault() {
+	service := s.service(s.options)
+	claims := userClaims("user-1")
+	claims.AuthTime = time.Now().Add(-24 * time.Hour).Unix()
+	refresh, _, _ := service.GetTokenPair(claims, Client{})
+	service.validation.now = func() time.Time { return time.Now().Add(50 * time.Minute) }
+
+	_, err := service.RefreshToken(refresh, Client{})
+
+	s.Nil(err)
+}
+
+func (s *jwtAuthServiceTestSuite) TestMapClaimsKeepOnlyCustomClaims() {
+	service := s.service(s.options)
+	claims := userClaims("user-1")
+	claims.Custom = JWTClaims{"role": "admin"}
+
+	decoded, err := service.GetClaim(mustSign(service.GetToken(claims)))
+
+	s.Require().Nil(err)
+	s.Equal(JWTClaims{"role": "admin"}, decoded.Custom)
+}
+
+func (s *jwtAuthServiceTestSuite) signRaw(claims jwt.MapClaims) string {
+	token, err := jwt.NewWithClaims(jwt.SigningMethodHS256, claims).SignedString([]byte(s.options.Key))
+	s.Require().NoError(err)
+	return token
+}
+
+func (s *jwtAuthServiceTestSuite) TestMalformedClaimsRejected() {
+	service := s.service(s.options)
+	token := s.signRaw(jwt.MapClaims{"use": types.RefreshTokenKey, "jti": 42, "fam": []string{"x"}})
+
+	_, err := service.GetClaim(token)
+
+	s.Require().NotNil(err)
+	code, _ := err.Resolve()
+	s.Equal(http.StatusUnauthorized, code)
+}
+
+func (s *jwtAuthServiceTestSuite) TestTokenPairFailsWhenSigningFails() {
+	public, _, _ := ed25519.GenerateKey(rand.Reader)
+	options := s.options
+	options.Algorithm = "EdDSA"
+	options.PublicKey = encodePublicKey(public)
+
+	refresh, auth, err := s.service(options).GetTokenPair(userClaims("user-1"), Client{})
+
+	s.Error(err)
+	s.Empty(refresh)
+	s.Empty(auth)
+}
+
+func (s *jwtAuthServiceTestSuite) TestRefreshTokenWithoutFamilyRejected() {
+	service := s.service(s.options)
+	token := s.signRaw(jwt.MapClaims{"use": types.RefreshTokenKey, config.UserIdClaim: "user-1"})
+
+	_, err := service.RefreshToken(token, Client{})
+
+	s.Require().NotNil(err)
+	code, _ := err.Resolve()
+	s.Equal(http.StatusUnauthorized, code)
+}
+
+func (s *jwtAuthServiceTestSuite) TestUnrecordedRefreshTokenRejected() {
+	refresh, _, _ := s.service(s.options).GetTokenPair(userClaims("user-1"), Client{})
+	restarted := s.service(s.options)
+
+	_, err := restarted.RefreshToken(refresh, Client{})
+
+	s.NotNil(err)
+}
+
+func (s *jwtAuthServiceTestSuite) TestReusedRefreshTokenRevokesFamily() {
+	service := s.service(s.options)
+	refresh, _, _ := service.GetTokenPair(userClaims("user-1"), Client{})
+	pair, err := service.RefreshToken(refresh, Client{})
+	s.Require().Nil(err)
+
+	_, err = service.RefreshToken(refresh, Client{})
+	s.NotNil(err)
+	_, err = service.RefreshToken(pair.Refresh, Client{})
+	s.NotNil(err)
+}
+
+func (s *jwtAuthServiceTestSuite) TestRotatedRefreshTokenReplaysWithinGrace() {
+	s.options.RefreshGracePeriod = time.Minute
+	service := s.service(s.options)
+	refresh, _, _ := service.GetTokenPair(userClaims("user-1"), Client{})
+	pair, err := service.RefreshToken(refresh, Client{})
+	s.Require().Nil(err)
+
+	replayed, err := service.RefreshToken(refresh, Client{})
+
+	s.Nil(err)
+	s.Equal(pair, replayed)
+	_, err = service.RefreshToken(pair.Refresh, Client{})
+	s.Nil(err)
+}
+
+func (s *jwtAuthServiceTestSuite) TestReuseAfterGraceRevokesFamily() {
+	s.options.RefreshGracePeriod = time.Minute
+	blacklister := NewInMemoryTokenBlacklister()
+	defer blacklister.Close()
+	service, _ := NewJWTAuthService[JWTClaims](s.options, blacklister)
+	refresh, _, _ := service.GetTokenPair(userClaims("user-1"), Client{})
+	pair, err := service.RefreshToken(refresh, Client{})
+	s.Require().Nil(err)
+	blacklister.now = func() time.Time { return time.Now().Add(2 * time.Minute) }
+
+	_, err = service.RefreshToken(refresh, Client{})
+	s.NotNil(err)
+	_, err = service.RefreshToken(pair.Refresh, Client{})
+	s.NotNil(err)
+}
+
+func (s *jwtAuthServiceTestSuite) TestGetRefreshTokenBecomesCurrent() {
+	service := s.service(s.options)
+	refresh := mustSign(service.GetRefreshToken(userClaims("user-1")))
+
+	_, err := service.RefreshToken(refresh, Client{})
+
+	s.Nil(err)
+}
+
+func TestJWTAuthService(t *testing.T) {
+	suite.Run(t, new(jwtAuthServiceTestSuite))
+}
@@ -0,0 +1,217 @@
+package services
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/dino16m/golearn-core/config"
+)
+
+type JWTClaims = map[string]interface{}
+
+// Audience is the aud claim, it is encoded as a string when it holds a
+// single audience
+type Audience []string
+
+func (a Audience) MarshalJSON() ([]byte, error) {
+	if len(a) == 1 {
+		return json.Marshal(a[0])
+	}
+	return json.Marshal([]string(a))
+}
+
+// Contains reports whether audience is one of the audiences
+func (a Audience) Contains(audience string) bool {
+	for _, aud := range a {
+		if aud == audience {
+			return true
+		}
+	}
+	return false
+}
+
+// RegisteredClaims are the claims set by JWTAuthService on every token
+// it issues. UserID is encoded under config.UserIdClaim
+type RegisteredClaims struct {
+	Issuer    string
+	Subject   string
+	Audience  Audience
+	ExpiresAt int64
+	NotBefore int64
+	IssuedAt  int64
+	ID        string
+	Use       string
+	Family    string
+	UserID    interface{}
+
+	// AuthTime is when the user authenticated to start the family of the
+	// token, it is kept when the family is refreshed
+	AuthTime int64
+	// IssuedAtNanos is the nanosecond within the second of IssuedAt the
+	// token was issued at, revocation watermarks are compared with it
+	IssuedAtNanos int64
+}
+
+func registeredClaimNames() []string {
+	return []string{"iss", "sub", "aud", "exp", "nbf", "iat", "jti", "use", "fam", "auth_time", "iat_ns", config.UserIdClaim}
+}
+
+// AuthenticatedWithin reports whether the user authenticated at most
+// maxAge ago, e.g. before allowing a sensitive operation
+func (c RegisteredClaims) AuthenticatedWithin(maxAge time.Duration) bool {
+	return c.AuthTime != 0 && time.Since(time.Unix(c.AuthTime, 0)) <= maxAge
+}
+
+// IssuedAtTime is when the token was issued, with the precision of
+// IssuedAtNanos
+func (c RegisteredClaims) IssuedAtTime() time.Time {
+	return time.Unix(c.IssuedAt, c.IssuedAtNanos)
+}
+
+func (c RegisteredClaims) toMap() map[string]interface{} {
+	claims := map[string]interface{}{}
+	for name, value := range map[string]string{
+		"iss": c.Issuer, "sub": c.Subject, "jti": c.ID, "use": c.Use, "fam": c.Family,
+	} {
+		if value != "" {
+			claims[name] = value
+		}
+	}
+	for name, value := range map[string]int64{
+		"exp": c.ExpiresAt, "nbf": c.NotBefore, "iat": c.IssuedAt, "auth_time": c.AuthTime,
+		"iat_ns": c.IssuedAtNanos,
+	} {
+		if value != 0 {
+			claims[name] = value
+		}
+	}
+	if len(c.Audience) > 0 {
+		claims["aud"] = c.Audience
+	}
+	if c.UserID != nil {
+		claims[config.UserIdClaim] = c.UserID
+	}
+	return claims
+}
+
+// fromMap reads the registered claims of a decoded token, it fails when
+// one of them has the wrong type
+func (c *RegisteredClaims) fromMap(claims map[string]interface{}) error {
+	stringClaims := map[string]*string{
+		"iss": &c.Issuer, "sub": &c.Subject, "jti": &c.ID, "use": &c.Use, "fam": &c.Family,
+	}
+	for name, field := range stringClaims {
+		value, ok := claims[name]
+		if !ok || value == nil {
+			continue
+		}
+		if *field, ok = value.(string); !ok {
+			return fmt.Errorf("claim %s is not a string", name)
+		}
+	}
+	numberClaims := map[string]*int64{
+		"exp": &c.ExpiresAt, "nbf": &c.NotBefore, "iat": &c.IssuedAt, "auth_time": &c.AuthTime,
+		"iat_ns": &c.IssuedAtNanos,
+	}
+	for name, field := range numberClaims {
+		value, ok := claims[name]
+		if !ok || value == nil {
+			continue
+		}
+		switch value := value.(type) {
+		case float64:
+			*field = int64(value)
+		case json.Number:
+			n, err := value.Int64()
+			if err != nil {
+				return fmt.Errorf("claim %s is not an integer", name)
+			}
+			*field = n
+		default:
+			return fmt.Errorf("claim %s is not a number", name)
+		}
+	}
+	switch aud := claims["aud"].(type) {
+	case nil:
+	case string:
+		c.Audience = Audience{aud}
+	case []interface{}:
+		c.Audience = make(Audience, len(aud))
+		for i, value := range aud {
+			audience, ok := value.(string)
+			if !ok {
+				return fmt.Errorf("claim aud is not a list of strings")
+			}
+			c.Audience[i] = audience
+		}
+	default:
+		return fmt.Errorf("claim aud is not a string or a list of strings")
+	}
+	c.UserID = claims[config.UserIdClaim]
+	return nil
+}
+
+func (c RegisteredClaims) MarshalJSON() ([]byte, error) {
+	return json.Marshal(c.toMap())
+}
+
+func (c *RegisteredClaims) UnmarshalJSON(data []byte) error {
+	var claims map[string]interface{}
+	if err := json.Unmarshal(data, &claims); err != nil {
+		return err
+	}
+	return c.fromMap(claims)
+}
+
+// Claims are the claims of a token, the fields of Custom are encoded next
+// to the registered claims so C must encode as a JSON object. JWTClaims
+// can be used as C to keep the custom claims in a map
+type Claims[C any] struct {
+	RegisteredClaims
+	Custom C
+}
+
+// Valid satisfies jwt.Claims, the registered claims are validated by the
+// service which verifies the token
+func (c Claims[C]) Valid() error {
+	return nil
+}
+
+func (c Claims[C]) MarshalJSON() ([]byte, error) {
+	claims := map[string]interface{}{}
+	custom, err := json.Marshal(c.Custom)
+	if err != nil {
+		return nil, err
+	}
+	if string(custom) != "null" {
+		decoder := json.NewDecoder(bytes.NewReader(custom))
+		decoder.UseNumber()
+		if err := decoder.Decode(&claims); err != nil {
+			return nil, fmt.Errorf("custom claims must encode as a JSON object: %w", err)
+		}
+	}
+	for _, name := range registeredClaimNames() {
+		delete(claims, name)
+	}
+	for name, value := range c.RegisteredClaims.toMap() {
+		claims[name] = value
+	}
+	return json.Marshal(claims)
+}
+
+func (c *Claims[C]) UnmarshalJSON(data []byte) error {
+	if err := c.RegisteredClaims.UnmarshalJSON(data); err != nil {
+		return err
+	}
+	if err := json.Unmarshal(data, &c.Custom); err != nil {
+		return err
+	}
+	if custom, ok := any(&c.Custom).(*JWTClaims); ok {
+		for _, name := range registeredClaimNames() {
+			delete(*custom, name)
+		}
+	}
+	return nil
+}
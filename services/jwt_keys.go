@@ -0,0 +1,111 @@
+package services
+
+import (
+	"crypto"
+	"crypto/ed25519"
+	"fmt"
+	"os"
+
+	"github.com/dino16m/golearn-core/config"
+	"github.com/golang-jwt/jwt"
+)
+
+const defaultAlgorithm = "HS256"
+
+// signingKey pairs a signing method with the keys it signs and verifies
+// with, signKey is nil when only the public key is known
+type signingKey struct {
+	method    jwt.SigningMethod
+	signKey   interface{}
+	verifyKey interface{}
+}
+
+func (k signingKey) canSign() bool {
+	return k.signKey != nil
+}
+
+func loadSigningKey(options config.JwtKey) (signingKey, error) {
+	algorithm := options.Algorithm
+	if algorithm == "" {
+		algorithm = defaultAlgorithm
+	}
+	method := jwt.GetSigningMethod(algorithm)
+	if method == nil || method == jwt.SigningMethodNone {
+		return signingKey{}, fmt.Errorf("unsupported signing algorithm %q", algorithm)
+	}
+
+	if _, ok := method.(*jwt.SigningMethodHMAC); ok {
+		if options.Key == "" {
+			return signingKey{}, fmt.Errorf("%s requires a Key", algorithm)
+		}
+		return signingKey{method: method, signKey: []byte(options.Key), verifyKey: []byte(options.Key)}, nil
+	}
+
+	privatePEM, err := readPEM(options.PrivateKey, options.PrivateKeyFile)
+	if err != nil {
+		return signingKey{}, err
+	}
+	publicPEM, err := readPEM(options.PublicKey, options.PublicKeyFile)
+	if err != nil {
+		return signingKey{}, err
+	}
+	if privatePEM == nil && publicPEM == nil {
+		return signingKey{}, fmt.Errorf("%s requires a PrivateKey or a PublicKey", algorithm)
+	}
+
+	key := signingKey{method: method}
+	if privatePEM != nil {
+		if key.signKey, err = parsePrivateKey(method, privatePEM); err != nil {
+			return signingKey{}, err
+		}
+		key.verifyKey = key.signKey.(crypto.Signer).Public()
+	}
+	if publicPEM != nil {
+		if key.verifyKey, err = parsePublicKey(method, publicPEM); err != nil {
+			return signingKey{}, err
+		}
+	}
+	return key, nil
+}
+
+func readPEM(value string, file string) ([]byte, error) {
+	if value != "" {
+		return []byte(value), nil
+	}
+	if file == "" {
+		return nil, nil
+	}
+	return os.ReadFile(file)
+}
+
+func parsePrivateKey(method jwt.SigningMethod, data []byte) (interface{}, error) {
+	switch method.(type) {
+	case *jwt.SigningMethodRSA, *jwt.SigningMethodRSAPSS:
+		return jwt.ParseRSAPrivateKeyFromPEM(data)
+	case *jwt.SigningMethodECDSA:
+		return jwt.ParseECPrivateKeyFromPEM(data)
+	case *jwt.SigningMethodEd25519:
+		key, err := jwt.ParseEdPrivateKeyFromPEM(data)
+		if err != nil {
+			return nil, err
+		}
+		return key.(ed25519.PrivateKey), nil
+	}
+	return nil, fmt.Errorf("unsupported signing algorithm %q", method.Alg())
+}
+
+func parsePublicKey(method jwt.SigningMethod, data []byte) (interface{}, error) {
+	switch method.(type) {
+	case *jwt.SigningMethodRSA, *jwt.SigningMethodRSAPSS:
+		return jwt.ParseRSAPublicKeyFromPEM(data)
+	case *jwt.SigningMethodECDSA:
+		return jwt.ParseECPublicKeyFromPEM(data)
+	case *jwt.SigningMethodEd25519:
+		key, err := jwt.ParseEdPublicKeyFromPEM(data)
+		if err != nil {
+			return nil, err
+		}
+		return key.(ed25519.PublicKey), nil
+	}
+	return nil, fmt.Errorf("unsupported signing algorithm %q", method.Alg())
+}
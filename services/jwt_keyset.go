@@ -0,0 +1,193 @@
+package services
+
+import (
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/dino16m/golearn-core/config"
+)
+
+type keyEntry struct {
+	signingKey
+	id       string
+	retireAt time.Time
+}
+
+func (k *keyEntry) retired(now time.Time) bool {
+	return !k.retireAt.IsZero() && !now.Before(k.retireAt)
+}
+
+// KeySet holds the keys of a JWTAuthService. Tokens are signed with the
+// current key and stamped with its ID in the kid header, they are verified
+// with whichever key their kid names until that key retires
+type KeySet struct {
+	keys    map[string]*keyEntry
+	order   []string
+	current string
+	now     func() time.Time
+	mu      sync.RWMutex
+}
+
+// NewKeySet constructs an empty KeySet
+func NewKeySet() *KeySet {
+	return &KeySet{keys: make(map[string]*keyEntry), now: time.Now}
+}
+
+// newKeySetFromOptions loads the key set of options, or a set holding
+// only the single key of options when it has no key set
+func newKeySetFromOptions(options config.JwtOptions) (*KeySet, error) {
+	keySet := NewKeySet()
+	keys := options.Keys
+	if len(keys) == 0 {
+		keys = []config.JwtKey{{
+			Algorithm:      options.Algorithm,
+			Key:            options.Key,
+			PrivateKey:     options.PrivateKey,
+			PublicKey:      options.PublicKey,
+			PrivateKeyFile: options.PrivateKeyFile,
+			PublicKeyFile:  options.PublicKeyFile,
+		}}
+	}
+	for _, key := range keys {
+		if err := keySet.Add(key); err != nil {
+			return nil, err
+		}
+	}
+	if options.CurrentKeyID != "" {
+		if err := keySet.SetCurrent(options.CurrentKeyID); err != nil {
+			return nil, err
+		}
+	}
+	return keySet, nil
+}
+
+// Add loads key into the set and makes it the current key
+func (ks *KeySet) Add(key config.JwtKey) error {
+	loaded, err := loadSigningKey(key)
+	if err != nil {
+		return fmt.Errorf("key %q: %w", key.ID, err)
+	}
+	ks.mu.Lock()
+	defer ks.mu.Unlock()
+	return ks.add(key, loaded)
+}
+
+// add makes a loaded key the current key, the caller must hold the lock
+func (ks *KeySet) add(key config.JwtKey, loaded signingKey) error {
+	if _, exists := ks.keys[key.ID]; exists {
+		return fmt.Errorf("key %q already exists", key.ID)
+	}
+	ks.keys[key.ID] = &keyEntry{signingKey: loaded, id: key.ID, retireAt: key.RetireAt}
+	ks.order = append(ks.order, key.ID)
+	ks.current = key.ID
+	return nil
+}
+
+// SetCurrent makes the key with the given ID sign new tokens
+func (ks *KeySet) SetCurrent(id string) error {
+	ks.mu.Lock()
+	defer ks.mu.Unlock()
+	if _, ok := ks.keys[id]; !ok {
+		return fmt.Errorf("unknown key %q", id)
+	}
+	ks.current = id
+	return nil
+}
+
+// Rotate adds key as the new current key and schedules the previous
+// current key to retire after overlap, once the tokens it signed expired.
+// The swap is atomic so concurrent rotations each retire the key they
+// replaced
+func (ks *KeySet) Rotate(key config.JwtKey, overlap time.Duration) error {
+	loaded, err := loadSigningKey(key)
+	if err != nil {
+		return fmt.Errorf("key %q: %w", key.ID, err)
+	}
+	ks.mu.Lock()
+	defer ks.mu.Unlock()
+	previous, ok := ks.keys[ks.current]
+	if err := ks.add(key, loaded); err != nil {
+		return err
+	}
+	if ok {
+		previous.retireAt = ks.now().Add(overlap)
+	}
+	return nil
+}
+
+// Retire schedules the key with the given ID to stop being accepted at
+func (ks *KeySet) Retire(id string, at time.Time) error {
+	ks.mu.Lock()
+	defer ks.mu.Unlock()
+	key, ok := ks.keys[id]
+	if !ok {
+		return fmt.Errorf("unknown key %q", id)
+	}
+	key.retireAt = at
+	return nil
+}
+
+// Prune removes the keys which have retired
+func (ks *KeySet) Prune() {
+	ks.mu.Lock()
+	defer ks.mu.Unlock()
+	now := ks.now()
+	active := []string{}
+	for _, id := range ks.order {
+		if ks.keys[id].retired(now) && id != ks.current {
+			delete(ks.keys, id)
+			continue
+		}
+		active = append(active, id)
+	}
+	ks.order = active
+}
+
+// IDs returns the IDs of the keys which are still accepted
+func (ks *KeySet) IDs() []string {
+	ks.mu.RLock()
+	defer ks.mu.RUnlock()
+	ids := []string{}
+	for _, id := range ks.order {
+		if !ks.keys[id].retired(ks.now()) {
+			ids = append(ids, id)
+		}
+	}
+	return ids
+}
+
+func (ks *KeySet) signingKey() (*keyEntry, error) {
+	ks.mu.RLock()
+	defer ks.mu.RUnlock()
+	key, ok := ks.keys[ks.current]
+	if !ok {
+		return nil, fmt.Errorf("no current signing key")
+	}
+	if !key.canSign() {
+		return nil, fmt.Errorf("no private key to sign %s tokens with", key.method.Alg())
+	}
+	return key, nil
+}
+
+// verificationKey returns the key named by kid, tokens without a kid
+// were signed before key rotation was set up and are verified with the
+// key without an ID, or the current key
+func (ks *KeySet) verificationKey(kid string, alg string) (*keyEntry, error) {
+	ks.mu.RLock()
+	defer ks.mu.RUnlock()
+	key, ok := ks.keys[kid]
+	if !ok && kid == "" {
+		key, ok = ks.keys[ks.current]
+	}
+	if !ok {
+		return nil, fmt.Errorf("unknown signing key %q", kid)
+	}
+	if key.retired(ks.now()) {
+		return nil, fmt.Errorf("signing key %q has been retired", kid)
+	}
+	if key.method.Alg() != alg {
+		return nil, fmt.Errorf("unexpected signing method: %v", alg)
+	}
+	return key, nil
+}
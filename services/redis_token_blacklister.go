@@ -0,0 +1,183 @@
+package services
+
+import (
+	"strconv"
+	"time"
+
+	"github.com/gomodule/redigo/redis"
+)
+
+// rotateScript replaces the current token of a family when it is the
+// expected one and the family is not revoked, the issued pair is kept for
+// replays when the grace ttl is positive.
+// KEYS: current key, revoked key, replay key.
+// ARGV: old jti, new jti, ttl and grace ttl in milliseconds, refresh token, auth token
+var rotateScript = redis.NewScript(3, `
+if redis.call("EXISTS", KEYS[2]) == 1 then
+	return 0
+end
+if redis.call("GET", KEYS[1]) ~= ARGV[1] then
+	return 0
+end
+redis.call("SET", KEYS[1], ARGV[2], "PX", ARGV[3])
+redis.call("DEL", KEYS[3])
+if tonumber(ARGV[4]) > 0 then
+	redis.call("HSET", KEYS[3], "jti", ARGV[1], "refresh", ARGV[5], "auth", ARGV[6])
+	redis.call("PEXPIRE", KEYS[3], ARGV[4])
+end
+return 1
+`)
+
+// watermarkScript raises the watermark of a user, an earlier watermark
+// never replaces a later one and the key keeps the longer ttl.
+// KEYS: watermark key. ARGV: watermark in unix nanoseconds, Lua compares
+// them as doubles which is precise enough to keep the later one, and ttl
+// in milliseconds
+var watermarkScript = redis.NewScript(1, `
+local current = tonumber(redis.call("GET", KEYS[1]) or "0")
+local ttl = redis.call("PTTL", KEYS[1])
+if tonumber(ARGV[1]) > current then
+	redis.call("SET", KEYS[1], ARGV[1])
+end
+redis.call("PEXPIRE", KEYS[1], math.max(ttl, tonumber(ARGV[2])))
+return 1
+`)
+
+// RedisTokenBlacklister is a RefreshValidator and TokenRevoker shared by every instance
+// using the same Redis, entries expire with the tokens they refer to
+type RedisTokenBlacklister struct {
+	pool   *redis.Pool
+	prefix string
+	now    func() time.Time
+}
+
+// NewRedisTokenBlacklister constructs a RedisTokenBlacklister storing its
+// keys under prefix, e.g. "myapp:auth:"
+func NewRedisTokenBlacklister(pool *redis.Pool, prefix string) *RedisTokenBlacklister {
+	return &RedisTokenBlacklister{pool: pool, prefix: prefix, now: time.Now}
+}
+
+func (blacklister *RedisTokenBlacklister) revokedKey(family string) string {
+	return blacklister.prefix + "revoked:" + family
+}
+
+func (blacklister *RedisTokenBlacklister) currentKey(family string) string {
+	return blacklister.prefix + "current:" + family
+}
+
+func (blacklister *RedisTokenBlacklister) replayKey(family string) string {
+	return blacklister.prefix + "replay:" + family
+}
+
+func (blacklister *RedisTokenBlacklister) revokedTokenKey(jti string) string {
+	return blacklister.prefix + "revoked-token:" + jti
+}
+
+func (blacklister *RedisTokenBlacklister) watermarkKey(userID string) string {
+	return blacklister.prefix + "watermark:" + userID
+}
+
+func (blacklister *RedisTokenBlacklister) BlackListFamily(family string, expiresAt time.Time) error {
+	return blacklister.set(blacklister.revokedKey(family), 1, expiresAt)
+}
+
+func (blacklister *RedisTokenBlacklister) SetCurrentJTI(family string, jti string, expiresAt time.Time) error {
+	return blacklister.set(blacklister.currentKey(family), jti, expiresAt)
+}
+
+func (blacklister *RedisTokenBlacklister) ValidateJTI(family string, jti string) (bool, error) {
+	conn := blacklister.pool.Get()
+	defer conn.Close()
+	values, err := redis.Strings(conn.Do("MGET", blacklister.currentKey(family), blacklister.revokedKey(family)))
+	if err != nil {
+		return false, err
+	}
+	return values[0] == jti && values[1] == "", nil
+}
+
+func (blacklister *RedisTokenBlacklister) RotateJTI(family string, oldJTI string, newJTI string, expiresAt time.Time, pair TokenPair, graceUntil time.Time) (bool, error) {
+	now := blacklister.now()
+	var graceTTL int64
+	if graceUntil.After(now) {
+		graceTTL = ttlMilliseconds(graceUntil.Sub(now))
+	}
+	conn := blacklister.pool.Get()
+	defer conn.Close()
+	rotated, err := redis.Int(rotateScript.Do(conn,
+		blacklister.currentKey(family), blacklister.revokedKey(family), blacklister.replayKey(family),
+		oldJTI, newJTI, ttlMilliseconds(expiresAt.Sub(now)), graceTTL, pair.Refresh, pair.Auth))
+	return rotated == 1, err
+}
+
+func (blacklister *RedisTokenBlacklister) Replay(family string, jti string) (TokenPair, bool, error) {
+	conn := blacklister.pool.Get()
+	defer conn.Close()
+	revoked, err := redis.Bool(conn.Do("EXISTS", blacklister.revokedKey(family)))
+	if err != nil || revoked {
+		return TokenPair{}, false, err
+	}
+	values, err := redis.Strings(conn.Do("HMGET", blacklister.replayKey(family), "jti", "refresh", "auth"))
+	if err != nil {
+		return TokenPair{}, false, err
+	}
+	if values[0] == "" || values[0] != jti {
+		return TokenPair{}, false, nil
+	}
+	return TokenPair{Refresh: values[1], Auth: values[2]}, true, nil
+}
+
+func (blacklister *RedisTokenBlacklister) RevokeToken(jti string, expiresAt time.Time) error {
+	return blacklister.set(blacklister.revokedTokenKey(jti), 1, expiresAt)
+}
+
+// RevokeUserTokens stores the watermark of the user until expiresAt
+func (blacklister *RedisTokenBlacklister) RevokeUserTokens(userID string, issuedBefore time.Time, expiresAt time.Time) error {
+	ttl := expiresAt.Sub(blacklister.now())
+	if ttl <= 0 {
+		return nil
+	}
+	conn := blacklister.pool.Get()
+	defer conn.Close()
+	_, err := watermarkScript.Do(conn, blacklister.watermarkKey(userID), issuedBefore.UnixNano(), ttlMilliseconds(ttl))
+	return err
+}
+
+func (blacklister *RedisTokenBlacklister) IsRevoked(jti string, userID string, issuedAt time.Time) (bool, error) {
+	conn := blacklister.pool.Get()
+	defer conn.Close()
+	values, err := redis.Strings(conn.Do("MGET", blacklister.revokedTokenKey(jti), blacklister.watermarkKey(userID)))
+	if err != nil {
+		return false, err
+	}
+	if jti != "" && values[0] != "" {
+		return true, nil
+	}
+	if values[1] == "" {
+		return false, nil
+	}
+	watermark, err := strconv.ParseInt(values[1], 10, 64)
+	if err != nil {
+		return false, err
+	}
+	return beforeWatermark(issuedAt, time.Unix(0, watermark)), nil
+}
+
+// set stores key until expiresAt, tokens which have already expired are
+// rejected by their exp claim so nothing is stored for them
+func (blacklister *RedisTokenBlacklister) set(key string, value interface{}, expiresAt time.Time) error {
+	ttl := expiresAt.Sub(blacklister.now())
+	if ttl <= 0 {
+		return nil
+	}
+	conn := blacklister.pool.Get()
+	defer conn.Close()
+	_, err := conn.Do("SET", key, value, "PX", ttlMilliseconds(ttl))
+	return err
+}
+
+func ttlMilliseconds(ttl time.Duration) int64 {
+	if milliseconds := ttl.Milliseconds(); milliseconds > 0 {
+		return milliseconds
+	}
+	return 1
+}
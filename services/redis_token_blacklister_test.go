@@ -0,0 +1,116 @@
+package services
+
+import (
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/dino16m/golearn-core/config"
+	"github.com/gomodule/redigo/redis"
+	"github.com/stretchr/testify/suite"
+)
+
+type redisTokenBlacklisterTestSuite struct {
+	suite.Suite
+	redis       *miniredis.Miniredis
+	pool        *redis.Pool
+	blacklister *RedisTokenBlacklister
+	now         time.Time
+}
+
+func (s *redisTokenBlacklisterTestSuite) SetupTest() {
+	s.redis = miniredis.RunT(s.T())
+	addr := s.redis.Addr()
+	s.pool = &redis.Pool{Dial: func() (redis.Conn, error) {
+		return redis.Dial("tcp", addr)
+	}}
+	s.now = time.Now()
+	s.blacklister = NewRedisTokenBlacklister(s.pool, "test:")
+	s.blacklister.now = func() time.Time { return s.now }
+}
+
+func (s *redisTokenBlacklisterTestSuite) TearDownTest() {
+	s.pool.Close()
+}
+
+func (s *redisTokenBlacklisterTestSuite) TestUnknownFamilyIsInvalid() {
+	valid, err := s.blacklister.ValidateJTI("family", "jti")
+
+	s.NoError(err)
+	s.False(valid)
+}
+
+func (s *redisTokenBlacklisterTestSuite) TestOnlyCurrentJTIIsValid() {
+	s.Require().NoError(s.blacklister.SetCurrentJTI("family", "jti-1", s.now.Add(time.Hour)))
+	s.Require().NoError(s.blacklister.SetCurrentJTI("family", "jti-2", s.now.Add(time.Hour)))
+
+	old, err := s.blacklister.ValidateJTI("family", "jti-1")
+	s.NoError(err)
+	s.False(old)
+	current, err := s.blacklister.ValidateJTI("family", "jti-2")
+	s.NoError(err)
+	s.True(current)
+}
+
+func (s *redisTokenBlacklisterTestSuite) TestBlacklistedFamily() {
+	s.Require().NoError(s.blacklister.SetCurrentJTI("family", "jti", s.now.Add(time.Hour)))
+	s.Require().NoError(s.blacklister.BlackListFamily("family", s.now.Add(time.Hour)))
+
+	valid, err := s.blacklister.ValidateJTI("family", "jti")
+
+	s.NoError(err)
+	s.False(valid)
+}
+
+func (s *redisTokenBlacklisterTestSuite) TestKeysExpireWithTheToken() {
+	s.Require().NoError(s.blacklister.SetCurrentJTI("family", "jti", s.now.Add(time.Hour)))
+
+	s.Equal(time.Hour, s.redis.TTL("test:current:family"))
+	s.redis.FastForward(time.Hour)
+	s.False(s.redis.Exists("test:current:family"))
+}
+
+func (s *redisTokenBlacklisterTestSuite) TestWatermarkExpiresWithTheLastToken() {
+	s.Require().NoError(s.blacklister.RevokeUserTokens("user-1", s.now, s.now.Add(time.Hour)))
+	s.Require().NoError(s.blacklister.RevokeUserTokens("user-1", s.now.Add(-time.Minute), s.now.Add(time.Minute)))
+
+	s.Equal(time.Hour, s.redis.TTL("test:watermark:user-1"))
+	s.redis.FastForward(time.Hour)
+	s.False(s.redis.Exists("test:watermark:user-1"))
+}
+
+func (s *redisTokenBlacklisterTestSuite) TestExpiredTokenIsNotStored() {
+	s.Require().NoError(s.blacklister.SetCurrentJTI("family", "jti", s.now.Add(-time.Minute)))
+
+	s.Empty(s.redis.Keys())
+}
+
+func (s *redisTokenBlacklisterTestSuite) TestSharedAcrossServiceInstances() {
+	options := config.JwtOptions{Key: "secret", Timeout: time.Minute, MaxRefresh: time.Hour}
+	first, _ := NewJWTAuthService[JWTClaims](options, NewRedisTokenBlacklister(s.pool, "test:"))
+	second, _ := NewJWTAuthService[JWTClaims](options, NewRedisTokenBlacklister(s.pool, "test:"))
+	refresh, _, _ := first.GetTokenPair(userClaims("user-1"), Client{})
+
+	_, err := first.RefreshToken(refresh, Client{})
+	s.Require().Nil(err)
+	_, err = second.RefreshToken(refresh, Client{})
+
+	s.NotNil(err)
+}
+
+func (s *redisTokenBlacklisterTestSuite) TestStoreErrorIsReported() {
+	options := config.JwtOptions{Key: "secret", Timeout: time.Minute, MaxRefresh: time.Hour}
+	service, _ := NewJWTAuthService[JWTClaims](options, s.blacklister)
+	refresh, _, _ := service.GetTokenPair(userClaims("user-1"), Client{})
+	s.redis.Close()
+
+	_, err := service.RefreshToken(refresh, Client{})
+
+	s.Require().NotNil(err)
+	code, _ := err.Resolve()
+	s.Equal(500, code)
+}
+
+func TestRedisTokenBlacklister(t *testing.T) {
+	suite.Run(t, new(redisTokenBlacklisterTestSuite))
+}
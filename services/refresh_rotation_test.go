@@ -0,0 +1,199 @@
+package services
+
+import (
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/dino16m/golearn-core/config"
+	"github.com/glebarez/sqlite"
+	"github.com/gomodule/redigo/redis"
+	"github.com/stretchr/testify/suite"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+)
+
+const parallelRefreshes = 20
+
+type refreshRotationTestSuite struct {
+	suite.Suite
+	newValidator func(t *testing.T) RefreshValidator
+	validator    RefreshValidator
+}
+
+func (s *refreshRotationTestSuite) SetupTest() {
+	s.validator = s.newValidator(s.T())
+}
+
+// parallel runs fn parallelRefreshes times at once and counts the calls
+// which report success
+func (s *refreshRotationTestSuite) parallel(fn func(i int) bool) int {
+	var wg sync.WaitGroup
+	var mu sync.Mutex
+	start := make(chan struct{})
+	wins := 0
+	for i := 0; i < parallelRefreshes; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			<-start
+			if fn(i) {
+				mu.Lock()
+				wins++
+				mu.Unlock()
+			}
+		}(i)
+	}
+	close(start)
+	wg.Wait()
+	return wins
+}
+
+func (s *refreshRotationTestSuite) TestExactlyOneRotationWins() {
+	expiresAt := time.Now().Add(time.Hour)
+	s.Require().NoError(s.validator.SetCurrentJTI("family", "old", expiresAt))
+	winners := make(chan string, parallelRefreshes)
+
+	wins := s.parallel(func(i int) bool {
+		newJTI := fmt.Sprint("new-", i)
+		rotated, err := s.validator.RotateJTI("family", "old", newJTI, expiresAt, TokenPair{}, time.Time{})
+		if rotated {
+			winners <- newJTI
+		}
+		return err == nil && rotated
+	})
+
+	s.Require().Equal(1, wins)
+	valid, err := s.validator.ValidateJTI("family", <-winners)
+	s.NoError(err)
+	s.True(valid)
+	valid, _ = s.validator.ValidateJTI("family", "old")
+	s.False(valid)
+}
+
+func (s *refreshRotationTestSuite) TestRotationOfBlacklistedFamilyFails() {
+	expiresAt := time.Now().Add(time.Hour)
+	s.Require().NoError(s.validator.SetCurrentJTI("family", "old", expiresAt))
+	s.Require().NoError(s.validator.BlackListFamily("family", expiresAt))
+
+	rotated, err := s.validator.RotateJTI("family", "old", "new", expiresAt, TokenPair{}, time.Time{})
+
+	s.NoError(err)
+	s.False(rotated)
+}
+
+func (s *refreshRotationTestSuite) TestExactlyOneParallelRefreshWins() {
+	options := config.JwtOptions{Key: "secret", Timeout: time.Minute, MaxRefresh: time.Hour}
+	service, _ := NewJWTAuthService[JWTClaims](options, s.validator)
+	refresh, _, err := service.GetTokenPair(userClaims("user-1"), Client{})
+	s.Require().NoError(err)
+
+	wins := s.parallel(func(int) bool {
+		_, err := service.RefreshToken(refresh, Client{})
+		return err == nil
+	})
+
+	s.Equal(1, wins)
+}
+
+func (s *refreshRotationTestSuite) TestRotatedTokenReplaysWithinGrace() {
+	expiresAt := time.Now().Add(time.Hour)
+	pair := TokenPair{Refresh: "refresh", Auth: "auth"}
+	s.Require().NoError(s.validator.SetCurrentJTI("family", "old", expiresAt))
+	rotated, err := s.validator.RotateJTI("family", "old", "new", expiresAt, pair, time.Now().Add(time.Minute))
+	s.Require().NoError(err)
+	s.Require().True(rotated)
+
+	replayed, ok, err := s.validator.Replay("family", "old")
+
+	s.NoError(err)
+	s.True(ok)
+	s.Equal(pair, replayed)
+	_, ok, _ = s.validator.Replay("family", "new")
+	s.False(ok)
+}
+
+func (s *refreshRotationTestSuite) TestNoReplayWithoutGrace() {
+	expiresAt := time.Now().Add(time.Hour)
+	s.Require().NoError(s.validator.SetCurrentJTI("family", "old", expiresAt))
+	_, err := s.validator.RotateJTI("family", "old", "new", expiresAt, TokenPair{Refresh: "refresh", Auth: "auth"}, time.Time{})
+	s.Require().NoError(err)
+
+	_, ok, err := s.validator.Replay("family", "old")
+
+	s.NoError(err)
+	s.False(ok)
+}
+
+func (s *refreshRotationTestSuite) TestNoReplayOfRevokedFamily() {
+	expiresAt := time.Now().Add(time.Hour)
+	s.Require().NoError(s.validator.SetCurrentJTI("family", "old", expiresAt))
+	_, err := s.validator.RotateJTI("family", "old", "new", expiresAt, TokenPair{Refresh: "refresh", Auth: "auth"}, time.Now().Add(time.Minute))
+	s.Require().NoError(err)
+	s.Require().NoError(s.validator.BlackListFamily("family", expiresAt))
+
+	_, ok, err := s.validator.Replay("family", "old")
+
+	s.NoError(err)
+	s.False(ok)
+}
+
+func (s *refreshRotationTestSuite) TestParallelRefreshesWithinGraceGetTheSamePair() {
+	options := config.JwtOptions{Key: "secret", Timeout: time.Minute, MaxRefresh: time.Hour, RefreshGracePeriod: time.Minute}
+	service, _ := NewJWTAuthService[JWTClaims](options, s.validator)
+	refresh, _, err := service.GetTokenPair(userClaims("user-1"), Client{})
+	s.Require().NoError(err)
+	pairs := make(chan TokenPair, parallelRefreshes)
+
+	wins := s.parallel(func(int) bool {
+		pair, err := service.RefreshToken(refresh, Client{})
+		pairs <- pair
+		return err == nil
+	})
+
+	s.Equal(parallelRefreshes, wins)
+	close(pairs)
+	first := <-pairs
+	for pair := range pairs {
+		s.Equal(first, pair)
+	}
+}
+
+func TestInMemoryRefreshRotation(t *testing.T) {
+	suite.Run(t, &refreshRotationTestSuite{newValidator: func(t *testing.T) RefreshValidator {
+		blacklister := NewInMemoryTokenBlacklister()
+		t.Cleanup(func() { blacklister.Close() })
+		return blacklister
+	}})
+}
+
+func TestRedisRefreshRotation(t *testing.T) {
+	suite.Run(t, &refreshRotationTestSuite{newValidator: func(t *testing.T) RefreshValidator {
+		addr := miniredis.RunT(t).Addr()
+		pool := &redis.Pool{Dial: func() (redis.Conn, error) {
+			return redis.Dial("tcp", addr)
+		}}
+		t.Cleanup(func() { pool.Close() })
+		return NewRedisTokenBlacklister(pool, "test:")
+	}})
+}
+
+func TestGormRefreshRotation(t *testing.T) {
+	suite.Run(t, &refreshRotationTestSuite{newValidator: func(t *testing.T) RefreshValidator {
+		db, err := gorm.Open(sqlite.Open("file:"+getJTI()+"?mode=memory&cache=shared"), &gorm.Config{Logger: logger.Discard})
+		if err != nil {
+			t.Fatal(err)
+		}
+		// SQLite allows a single writer, the compare and swap is a single
+		// conditional UPDATE so serialising statements does not hide races
+		sqlDB, _ := db.DB()
+		sqlDB.SetMaxOpenConns(1)
+		store := NewGormRefreshStore(db)
+		if err := store.Migrate(); err != nil {
+			t.Fatal(err)
+		}
+		return store
+	}})
+}
@@ -0,0 +1,36 @@
+package services
+
+import "time"
+
+// Client describes who a token pair is issued to
+type Client struct {
+	UserAgent string
+	IP        string
+}
+
+// Session is a refresh token family as shown on an active sessions page
+type Session struct {
+	Family     string    `json:"id"`
+	UserID     string    `json:"userId"`
+	UserAgent  string    `json:"userAgent"`
+	IP         string    `json:"ip"`
+	CreatedAt  time.Time `json:"createdAt"`
+	LastUsedAt time.Time `json:"lastUsedAt"`
+	ExpiresAt  time.Time `json:"expiresAt"`
+}
+
+// SessionStore is a RefreshValidator which keeps track of the sessions
+// refresh token families belong to
+type SessionStore interface {
+	RefreshValidator
+	// SaveSession records jti as the current refresh token of the session,
+	// it is called when a family starts and on every refresh
+	SaveSession(session Session, jti string, expiresAt time.Time) error
+	// ListSessions returns the sessions of a user which are not revoked
+	// or expired, most recently used first
+	ListSessions(userID string) ([]Session, error)
+	// RevokeSession revokes a session of a user, it reports false when the
+	// user has no such session
+	RevokeSession(userID string, family string) (bool, error)
+	RevokeAllSessions(userID string) error
+}
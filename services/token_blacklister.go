@@ -1,28 +1,209 @@
 package services
 
+import (
+	"sync"
+	"time"
+)
+
+const blacklisterCleanupInterval = time.Minute
+
+type watermark struct {
+	issuedBefore time.Time
+	expiresAt    time.Time
+}
+
+type currentJTI struct {
+	jti        string
+	expiresAt  time.Time
+	previous   string
+	replay     TokenPair
+	graceUntil time.Time
+}
+
+// InMemoryTokenBlacklister is a RefreshValidator and TokenRevoker for a
+// single process. Entries are dropped once the tokens they refer to have
+// expired, a janitor goroutine evicts them until Close is called
 type InMemoryTokenBlacklister struct {
-	blacklistedFamilies map[string]bool
-	blacklistedJTIs     map[string]bool
+	blacklistedFamilies map[string]time.Time
+	currentJTIs         map[string]currentJTI
+	revokedTokens       map[string]time.Time
+	watermarks          map[string]watermark
+	now                 func() time.Time
+	mu                  sync.Mutex
+	stop                chan struct{}
+	stopOnce            sync.Once
 }
 
 func NewInMemoryTokenBlacklister() *InMemoryTokenBlacklister {
-	return &InMemoryTokenBlacklister{
-		blacklistedFamilies: make(map[string]bool),
-		blacklistedJTIs:     make(map[string]bool),
+	blacklister := &InMemoryTokenBlacklister{
+		blacklistedFamilies: make(map[string]time.Time),
+		currentJTIs:         make(map[string]currentJTI),
+		revokedTokens:       make(map[string]time.Time),
+		watermarks:          make(map[string]watermark),
+		now:                 time.Now,
+		stop:                make(chan struct{}),
+	}
+	go blacklister.janitor(blacklisterCleanupInterval)
+	return blacklister
+}
+
+func (blacklister *InMemoryTokenBlacklister) BlackListFamily(family string, expiresAt time.Time) error {
+	blacklister.mu.Lock()
+	defer blacklister.mu.Unlock()
+	blacklister.blacklistedFamilies[family] = expiresAt
+	return nil
+}
+
+func (blacklister *InMemoryTokenBlacklister) SetCurrentJTI(family string, jti string, expiresAt time.Time) error {
+	blacklister.mu.Lock()
+	defer blacklister.mu.Unlock()
+	blacklister.currentJTIs[family] = currentJTI{jti: jti, expiresAt: expiresAt}
+	return nil
+}
+
+func (blacklister *InMemoryTokenBlacklister) ValidateJTI(family string, jti string) (bool, error) {
+	blacklister.mu.Lock()
+	defer blacklister.mu.Unlock()
+	if current, ok := blacklister.currentJTIs[family]; ok {
+		blacklister.dropExpiredReplay(family, current, blacklister.now())
+	}
+	return blacklister.valid(family, jti), nil
+}
+
+func (blacklister *InMemoryTokenBlacklister) RotateJTI(family string, oldJTI string, newJTI string, expiresAt time.Time, pair TokenPair, graceUntil time.Time) (bool, error) {
+	blacklister.mu.Lock()
+	defer blacklister.mu.Unlock()
+	if !blacklister.valid(family, oldJTI) {
+		return false, nil
+	}
+	blacklister.currentJTIs[family] = currentJTI{
+		jti:        newJTI,
+		expiresAt:  expiresAt,
+		previous:   oldJTI,
+		replay:     pair,
+		graceUntil: graceUntil,
+	}
+	return true, nil
+}
+
+func (blacklister *InMemoryTokenBlacklister) Replay(family string, jti string) (TokenPair, bool, error) {
+	blacklister.mu.Lock()
+	defer blacklister.mu.Unlock()
+	now := blacklister.now()
+	if blacklisted(blacklister.blacklistedFamilies, family, now) {
+		return TokenPair{}, false, nil
+	}
+	current, ok := blacklister.currentJTIs[family]
+	if !ok || current.previous != jti || !now.Before(current.graceUntil) {
+		return TokenPair{}, false, nil
 	}
+	return current.replay, true, nil
 }
 
-func (blacklister *InMemoryTokenBlacklister) BlackListFamily(family string) {
-	blacklister.blacklistedFamilies[family] = true
+func (blacklister *InMemoryTokenBlacklister) RevokeToken(jti string, expiresAt time.Time) error {
+	blacklister.mu.Lock()
+	defer blacklister.mu.Unlock()
+	blacklister.revokedTokens[jti] = expiresAt
+	return nil
 }
 
-func (blacklister *InMemoryTokenBlacklister) InvalidateJTI(jti string) {
-	blacklister.blacklistedJTIs[jti] = true
+func (blacklister *InMemoryTokenBlacklister) RevokeUserTokens(userID string, issuedBefore time.Time, expiresAt time.Time) error {
+	blacklister.mu.Lock()
+	defer blacklister.mu.Unlock()
+	current := blacklister.watermarks[userID]
+	if issuedBefore.After(current.issuedBefore) {
+		current.issuedBefore = issuedBefore
+	}
+	if expiresAt.After(current.expiresAt) {
+		current.expiresAt = expiresAt
+	}
+	blacklister.watermarks[userID] = current
+	return nil
+}
+
+func (blacklister *InMemoryTokenBlacklister) IsRevoked(jti string, userID string, issuedAt time.Time) (bool, error) {
+	blacklister.mu.Lock()
+	defer blacklister.mu.Unlock()
+	if jti != "" && blacklisted(blacklister.revokedTokens, jti, blacklister.now()) {
+		return true, nil
+	}
+	watermark, ok := blacklister.watermarks[userID]
+	return ok && beforeWatermark(issuedAt, watermark.issuedBefore), nil
 }
 
-func (blacklister *InMemoryTokenBlacklister) ValidateJTI(family string, jti string) bool {
-	familyBlacklisted := blacklister.blacklistedFamilies[family]
-	jtiBlacklisted := blacklister.blacklistedJTIs[jti]
+// valid reports whether jti is the current token of family, the caller
+// must hold the lock
+func (blacklister *InMemoryTokenBlacklister) valid(family string, jti string) bool {
+	now := blacklister.now()
+	if blacklisted(blacklister.blacklistedFamilies, family, now) {
+		return false
+	}
+	current, ok := blacklister.currentJTIs[family]
+	return ok && current.jti == jti && now.Before(current.expiresAt)
+}
+
+// Close stops the janitor, the blacklister stays usable but expired
+// entries are no longer evicted
+func (blacklister *InMemoryTokenBlacklister) Close() error {
+	blacklister.stopOnce.Do(func() {
+		close(blacklister.stop)
+	})
+	return nil
+}
+
+func (blacklister *InMemoryTokenBlacklister) janitor(interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ticker.C:
+			blacklister.evictExpired()
+		case <-blacklister.stop:
+			return
+		}
+	}
+}
+
+func (blacklister *InMemoryTokenBlacklister) evictExpired() {
+	blacklister.mu.Lock()
+	defer blacklister.mu.Unlock()
+	now := blacklister.now()
+	for family, expiresAt := range blacklister.blacklistedFamilies {
+		if !now.Before(expiresAt) {
+			delete(blacklister.blacklistedFamilies, family)
+		}
+	}
+	for family, current := range blacklister.currentJTIs {
+		if !now.Before(current.expiresAt) {
+			delete(blacklister.currentJTIs, family)
+			continue
+		}
+		blacklister.dropExpiredReplay(family, current, now)
+	}
+	for jti, expiresAt := range blacklister.revokedTokens {
+		if !now.Before(expiresAt) {
+			delete(blacklister.revokedTokens, jti)
+		}
+	}
+	for userID, watermark := range blacklister.watermarks {
+		if !now.Before(watermark.expiresAt) {
+			delete(blacklister.watermarks, userID)
+		}
+	}
+}
+
+// dropExpiredReplay forgets the pair kept for a replay of family once its
+// grace period is over so live tokens are not held until the family
+// expires, the caller must hold the lock
+func (blacklister *InMemoryTokenBlacklister) dropExpiredReplay(family string, current currentJTI, now time.Time) {
+	if current.graceUntil.IsZero() || now.Before(current.graceUntil) {
+		return
+	}
+	blacklister.currentJTIs[family] = currentJTI{jti: current.jti, expiresAt: current.expiresAt}
+}
 
-	return !(familyBlacklisted || jtiBlacklisted)
+// blacklisted reports whether key is in entries and has not expired
+func blacklisted(entries map[string]time.Time, key string, now time.Time) bool {
+	expiresAt, ok := entries[key]
+	return ok && now.Before(expiresAt)
 }
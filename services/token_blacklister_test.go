@@ -0,0 +1,166 @@
+package services
+
+import (
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/suite"
+)
+
+type inMemoryTokenBlacklisterTestSuite struct {
+	suite.Suite
+	blacklister *InMemoryTokenBlacklister
+	now         time.Time
+}
+
+func (s *inMemoryTokenBlacklisterTestSuite) SetupTest() {
+	s.now = time.Now()
+	s.blacklister = NewInMemoryTokenBlacklister()
+	s.blacklister.now = func() time.Time { return s.now }
+}
+
+func (s *inMemoryTokenBlacklisterTestSuite) TearDownTest() {
+	s.blacklister.Close()
+}
+
+func (s *inMemoryTokenBlacklisterTestSuite) TestUnknownFamilyIsInvalid() {
+	valid, err := s.blacklister.ValidateJTI("family", "jti")
+
+	s.NoError(err)
+	s.False(valid)
+}
+
+func (s *inMemoryTokenBlacklisterTestSuite) TestOnlyCurrentJTIIsValid() {
+	s.blacklister.SetCurrentJTI("family", "jti-1", s.now.Add(time.Hour))
+	s.blacklister.SetCurrentJTI("family", "jti-2", s.now.Add(time.Hour))
+
+	old, _ := s.blacklister.ValidateJTI("family", "jti-1")
+	current, _ := s.blacklister.ValidateJTI("family", "jti-2")
+
+	s.False(old)
+	s.True(current)
+}
+
+func (s *inMemoryTokenBlacklisterTestSuite) TestBlacklistedFamily() {
+	s.blacklister.SetCurrentJTI("family", "jti", s.now.Add(time.Hour))
+	s.blacklister.BlackListFamily("family", s.now.Add(time.Hour))
+
+	valid, _ := s.blacklister.ValidateJTI("family", "jti")
+
+	s.False(valid)
+}
+
+func (s *inMemoryTokenBlacklisterTestSuite) TestEntriesExpire() {
+	s.blacklister.SetCurrentJTI("family", "jti", s.now.Add(time.Hour))
+	s.now = s.now.Add(time.Hour)
+
+	valid, _ := s.blacklister.ValidateJTI("family", "jti")
+
+	s.False(valid)
+}
+
+func (s *inMemoryTokenBlacklisterTestSuite) TestEvictExpired() {
+	s.blacklister.SetCurrentJTI("old", "jti-1", s.now.Add(time.Minute))
+	s.blacklister.SetCurrentJTI("new", "jti-2", s.now.Add(time.Hour))
+	s.blacklister.BlackListFamily("family", s.now.Add(time.Minute))
+	s.now = s.now.Add(30 * time.Minute)
+
+	s.blacklister.evictExpired()
+
+	s.Len(s.blacklister.currentJTIs, 1)
+	s.Contains(s.blacklister.currentJTIs, "new")
+	s.Empty(s.blacklister.blacklistedFamilies)
+}
+
+func (s *inMemoryTokenBlacklisterTestSuite) TestEvictExpiredWatermarks() {
+	s.blacklister.RevokeUserTokens("old", s.now, s.now.Add(time.Minute))
+	s.blacklister.RevokeUserTokens("new", s.now, s.now.Add(time.Hour))
+	s.now = s.now.Add(30 * time.Minute)
+
+	s.blacklister.evictExpired()
+
+	s.Len(s.blacklister.watermarks, 1)
+	s.Contains(s.blacklister.watermarks, "new")
+}
+
+func (s *inMemoryTokenBlacklisterTestSuite) TestEvictExpiredDropsReplayAfterGrace() {
+	pair := TokenPair{Refresh: "refresh", Auth: "auth"}
+	s.blacklister.SetCurrentJTI("expired", "jti-1", s.now.Add(time.Hour))
+	s.blacklister.SetCurrentJTI("within", "jti-3", s.now.Add(time.Hour))
+	s.blacklister.RotateJTI("expired", "jti-1", "jti-2", s.now.Add(time.Hour), pair, s.now.Add(time.Minute))
+	s.blacklister.RotateJTI("within", "jti-3", "jti-4", s.now.Add(time.Hour), pair, s.now.Add(time.Hour))
+	s.now = s.now.Add(time.Minute)
+
+	s.blacklister.evictExpired()
+
+	s.Equal(currentJTI{jti: "jti-2", expiresAt: s.now.Add(59 * time.Minute)}, s.blacklister.currentJTIs["expired"])
+	s.Equal(pair, s.blacklister.currentJTIs["within"].replay)
+}
+
+func (s *inMemoryTokenBlacklisterTestSuite) TestValidateJTIDropsReplayAfterGrace() {
+	s.blacklister.SetCurrentJTI("family", "jti-1", s.now.Add(time.Hour))
+	s.blacklister.RotateJTI("family", "jti-1", "jti-2", s.now.Add(time.Hour),
+		TokenPair{Refresh: "refresh", Auth: "auth"}, s.now.Add(time.Minute))
+	s.now = s.now.Add(time.Minute)
+
+	valid, _ := s.blacklister.ValidateJTI("family", "jti-2")
+
+	s.True(valid)
+	s.Empty(s.blacklister.currentJTIs["family"].replay)
+	s.Empty(s.blacklister.currentJTIs["family"].previous)
+}
+
+func (s *inMemoryTokenBlacklisterTestSuite) TestJanitorStopsOnClose() {
+	blacklister := &InMemoryTokenBlacklister{
+		blacklistedFamilies: make(map[string]time.Time),
+		currentJTIs:         make(map[string]currentJTI),
+		now:                 time.Now,
+		stop:                make(chan struct{}),
+	}
+	blacklister.SetCurrentJTI("family", "jti", time.Now())
+	done := make(chan struct{})
+	go func() {
+		blacklister.janitor(time.Millisecond)
+		close(done)
+	}()
+	s.Eventually(func() bool {
+		blacklister.mu.Lock()
+		defer blacklister.mu.Unlock()
+		return len(blacklister.currentJTIs) == 0
+	}, time.Second, time.Millisecond)
+
+	s.NoError(blacklister.Close())
+	s.NoError(blacklister.Close())
+	s.Eventually(func() bool {
+		select {
+		case <-done:
+			return true
+		default:
+			return false
+		}
+	}, time.Second, time.Millisecond)
+}
+
+func (s *inMemoryTokenBlacklisterTestSuite) TestConcurrentUse() {
+	var wg sync.WaitGroup
+	for i := 0; i < 50; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			family := fmt.Sprint("family-", i)
+			s.blacklister.SetCurrentJTI(family, "jti", s.now.Add(time.Hour))
+			s.blacklister.BlackListFamily("revoked", s.now.Add(time.Hour))
+			s.blacklister.ValidateJTI(family, "jti")
+			s.blacklister.evictExpired()
+		}(i)
+	}
+	wg.Wait()
+
+	s.Len(s.blacklister.currentJTIs, 50)
+}
+
+func TestInMemoryTokenBlacklister(t *testing.T) {
+	suite.Run(t, new(inMemoryTokenBlacklisterTestSuite))
+}
@@ -0,0 +1,35 @@
+package services
+
+import (
+	"time"
+)
+
+// TokenRevoker revokes tokens before they expire, either a single token by
+// its jti or every token of a user issued before a watermark
+type TokenRevoker interface {
+	// RevokeToken revokes the token jti until it expires at expiresAt
+	RevokeToken(jti string, expiresAt time.Time) error
+	// RevokeUserTokens revokes the tokens of userID issued at or before
+	// issuedBefore, a later watermark replaces an earlier one. The
+	// watermark is kept until expiresAt, once every token issued before it
+	// has expired
+	RevokeUserTokens(userID string, issuedBefore time.Time, expiresAt time.Time) error
+	// IsRevoked reports whether the token jti of userID issued at issuedAt
+	// has been revoked
+	IsRevoked(jti string, userID string, issuedAt time.Time) (bool, error)
+}
+
+// RevocationChecker reports whether verified claims belong to a token
+// which has been revoked, the JWTAuthMiddleware checks it when the token
+// verifier implements it
+type RevocationChecker interface {
+	Revoked(claims RegisteredClaims) (bool, error)
+}
+
+// beforeWatermark reports whether a token issued at issuedAt is revoked by
+// watermark. Tokens are compared with the nanosecond precision of iat_ns so
+// a login right after the watermark is not revoked, tokens without iat_ns
+// are revoked in the whole second of the watermark
+func beforeWatermark(issuedAt time.Time, watermark time.Time) bool {
+	return !issuedAt.After(watermark)
+}
@@ -0,0 +1,196 @@
+package services
+
+import (
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/dino16m/golearn-core/config"
+	"github.com/glebarez/sqlite"
+	"github.com/gomodule/redigo/redis"
+	"github.com/stretchr/testify/suite"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+)
+
+type tokenRevokerTestSuite struct {
+	suite.Suite
+	newRevoker func(t *testing.T) TokenRevoker
+	revoker    TokenRevoker
+}
+
+func (s *tokenRevokerTestSuite) SetupTest() {
+	s.revoker = s.newRevoker(s.T())
+}
+
+func (s *tokenRevokerTestSuite) TestUnknownTokenIsNotRevoked() {
+	revoked, err := s.revoker.IsRevoked("jti", "user-1", time.Now())
+
+	s.NoError(err)
+	s.False(revoked)
+}
+
+func (s *tokenRevokerTestSuite) TestRevokedToken() {
+	s.Require().NoError(s.revoker.RevokeToken("jti", time.Now().Add(time.Hour)))
+
+	revoked, err := s.revoker.IsRevoked("jti", "user-1", time.Now())
+	s.NoError(err)
+	s.True(revoked)
+	revoked, _ = s.revoker.IsRevoked("other", "user-1", time.Now())
+	s.False(revoked)
+}
+
+func (s *tokenRevokerTestSuite) TestRevocationOfExpiredTokenIsIgnored() {
+	s.Require().NoError(s.revoker.RevokeToken("jti", time.Now().Add(-time.Second)))
+
+	revoked, err := s.revoker.IsRevoked("jti", "user-1", time.Now())
+
+	s.NoError(err)
+	s.False(revoked)
+}
+
+func (s *tokenRevokerTestSuite) TestWatermarkRevokesEarlierTokens() {
+	watermark := time.Now()
+	s.Require().NoError(s.revoker.RevokeUserTokens("user-1", watermark, watermark.Add(time.Hour)))
+
+	before, err := s.revoker.IsRevoked("jti", "user-1", watermark.Add(-time.Minute))
+	s.NoError(err)
+	s.True(before)
+	atWatermark, _ := s.revoker.IsRevoked("jti", "user-1", watermark)
+	s.True(atWatermark)
+	after, _ := s.revoker.IsRevoked("jti", "user-1", watermark.Add(time.Millisecond))
+	s.False(after)
+	otherUser, _ := s.revoker.IsRevoked("jti", "user-2", watermark.Add(-time.Minute))
+	s.False(otherUser)
+}
+
+func (s *tokenRevokerTestSuite) TestEarlierWatermarkDoesNotReplaceLater() {
+	watermark := time.Now()
+	s.Require().NoError(s.revoker.RevokeUserTokens("user-1", watermark, watermark.Add(time.Hour)))
+	s.Require().NoError(s.revoker.RevokeUserTokens("user-1", watermark.Add(-time.Hour), watermark))
+
+	revoked, err := s.revoker.IsRevoked("jti", "user-1", watermark.Add(-time.Minute))
+
+	s.NoError(err)
+	s.True(revoked)
+}
+
+func TestInMemoryTokenRevoker(t *testing.T) {
+	suite.Run(t, &tokenRevokerTestSuite{newRevoker: func(t *testing.T) TokenRevoker {
+		blacklister := NewInMemoryTokenBlacklister()
+		t.Cleanup(func() { blacklister.Close() })
+		return blacklister
+	}})
+}
+
+func TestRedisTokenRevoker(t *testing.T) {
+	suite.Run(t, &tokenRevokerTestSuite{newRevoker: func(t *testing.T) TokenRevoker {
+		addr := miniredis.RunT(t).Addr()
+		pool := &redis.Pool{Dial: func() (redis.Conn, error) {
+			return redis.Dial("tcp", addr)
+		}}
+		t.Cleanup(func() { pool.Close() })
+		return NewRedisTokenBlacklister(pool, "test:")
+	}})
+}
+
+func TestGormTokenRevoker(t *testing.T) {
+	suite.Run(t, &tokenRevokerTestSuite{newRevoker: func(t *testing.T) TokenRevoker {
+		db, err := gorm.Open(sqlite.Open("file:"+getJTI()+"?mode=memory&cache=shared"), &gorm.Config{Logger: logger.Discard})
+		if err != nil {
+			t.Fatal(err)
+		}
+		store := NewGormRefreshStore(db)
+		if err := store.Migrate(); err != nil {
+			t.Fatal(err)
+		}
+		return store
+	}})
+}
+
+type logoutTestSuite struct {
+	suite.Suite
+	blacklister *InMemoryTokenBlacklister
+	service     JWTAuthService[JWTClaims]
+}
+
+func (s *logoutTestSuite) SetupTest() {
+	options := config.JwtOptions{Key: "secret", Timeout: time.Minute, MaxRefresh: time.Hour}
+	s.blacklister = NewInMemoryTokenBlacklister()
+	service, err := NewJWTAuthService[JWTClaims](options, s.blacklister)
+	s.Require().NoError(err)
+	s.service = service
+}
+
+func (s *logoutTestSuite) TearDownTest() {
+	s.blacklister.Close()
+}
+
+func (s *logoutTestSuite) login() (refresh string, claims Claims[JWTClaims]) {
+	refresh, auth, err := s.service.GetTokenPair(userClaims("user-1"), Client{})
+	s.Require().NoError(err)
+	claims, appErr := s.service.GetClaim(auth)
+	s.Require().Nil(appErr)
+	return refresh, claims
+}
+
+func (s *logoutTestSuite) TestAuthTokensHaveAJTI() {
+	_, claims := s.login()
+
+	s.NotEmpty(claims.ID)
+}
+
+func (s *logoutTestSuite) TestLogoutRevokesAuthTokenAndFamily() {
+	refresh, claims := s.login()
+	_, other := s.login()
+
+	s.Nil(s.service.Logout(claims))
+
+	revoked, err := s.service.Revoked(claims.RegisteredClaims)
+	s.NoError(err)
+	s.True(revoked)
+	revoked, _ = s.service.Revoked(other.RegisteredClaims)
+	s.False(revoked)
+	_, appErr := s.service.RefreshToken(refresh, Client{})
+	s.NotNil(appErr)
+}
+
+func (s *logoutTestSuite) TestLogoutAllRevokesEveryEarlierToken() {
+	refresh, claims := s.login()
+	_, other := s.login()
+
+	s.Nil(s.service.LogoutAll(claims))
+
+	revoked, _ := s.service.Revoked(claims.RegisteredClaims)
+	s.True(revoked)
+	revoked, _ = s.service.Revoked(other.RegisteredClaims)
+	s.True(revoked)
+	_, appErr := s.service.RefreshToken(refresh, Client{})
+	s.NotNil(appErr)
+}
+
+func (s *logoutTestSuite) TestLoginRightAfterLogoutAllIsNotRevoked() {
+	_, claims := s.login()
+	s.Require().Nil(s.service.LogoutAll(claims))
+
+	_, fresh := s.login()
+
+	revoked, err := s.service.Revoked(fresh.RegisteredClaims)
+	s.NoError(err)
+	s.False(revoked)
+}
+
+func (s *logoutTestSuite) TestLogoutAllRequiresATokenRevoker() {
+	service, _ := NewJWTAuthService[JWTClaims](config.JwtOptions{Key: "secret"}, refreshValidatorOnly{s.blacklister})
+
+	s.NotNil(service.LogoutAll(userClaims("user-1")))
+}
+
+// refreshValidatorOnly hides every method but those of RefreshValidator
+type refreshValidatorOnly struct {
+	RefreshValidator
+}
+
+func TestLogout(t *testing.T) {
+	suite.Run(t, new(logoutTestSuite))
+}